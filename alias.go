@@ -0,0 +1,178 @@
+package nfigure
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nflex"
+	"github.com/muir/reflectutils"
+	"github.com/pkg/errors"
+)
+
+var _ recurseFieldFiller = FileFiller{}
+
+// DeprecatedKey describes a configuration file that uses an alias
+// instead of the current name for a key.
+type DeprecatedKey struct {
+	File    string // the file, with the line and column when known, like "config.yaml:3:1"
+	Alias   string // the key path as it is in the file, like "server.timeout"
+	Key     string // the alias replaced by the current name, like "server.requestTimeout"
+	Ignored bool   // the file also has the current name (or an earlier alias) so the alias was not used
+}
+
+// WithDeprecatedKeyFunc sets a function to call when a configuration
+// file uses an alias.  It can be used to warn about keys that have been
+// renamed.  The function is called while Configure() fills the models,
+// at most once for each alias in each file.
+func WithDeprecatedKeyFunc(f func(DeprecatedKey)) FileFillerOpts {
+	return func(s *FileFiller) {
+		s.deprecated = &deprecationReporter{
+			f:        f,
+			reported: make(map[DeprecatedKey]struct{}),
+		}
+	}
+}
+
+// recurseField handles the config tag, including aliases:
+//
+//	type Config struct {
+//		RequestTimeout time.Duration `config:"requestTimeout,alias=timeout|req_timeout"`
+//	}
+//
+// Each file is considered separately.  If a file has the current name,
+// its aliases are ignored in that file.  Otherwise the first alias (in
+// the order listed) that the file has is used.  Between files, the
+// usual layering of files applies: a file that uses an alias is
+// treated the same as a file that uses the current name.
+func (s FileFiller) recurseField(name string, t reflect.Type, tag reflectutils.Tag) (Filler, error) {
+	var fileTag fileTag
+	if tag.Tag != "" {
+		err := tag.Fill(&fileTag)
+		if err != nil {
+			return nil, commonerrors.ProgrammerError(errors.Wrap(err, tag.Tag))
+		}
+		switch fileTag.Name {
+		case "-":
+			debug("source: recurseField w/", tag.Tag, ": skip")
+			return nil, nil
+		case "":
+		default:
+			name = fileTag.Name
+		}
+	}
+	if fileTag.Alias == "" {
+		return s.Recurse(name)
+	}
+	return s.recurse(name, strings.Split(fileTag.Alias, "|"))
+}
+
+// recurseOrigin looks up name in one file.  If the file does not have
+// name, the first of aliases that it has is used instead.  The key that
+// was used is returned.
+func (s FileFiller) recurseOrigin(origin fileOrigin, name string, aliases []string) (fileOrigin, string, error) {
+	recursed, err := origin.recurse(name)
+	if err != nil {
+		return recursed, name, err
+	}
+	key := name
+	current := strings.Join(recursed.keyPath, ".")
+	for _, alias := range aliases {
+		old, err := origin.recurse(alias)
+		if err != nil {
+			return old, alias, err
+		}
+		if old.source == nil {
+			continue
+		}
+		s.deprecated.report(DeprecatedKey{
+			File:    old.file(),
+			Alias:   strings.Join(old.keyPath, "."),
+			Key:     current,
+			Ignored: recursed.source != nil,
+		})
+		if recursed.source == nil {
+			recursed = old
+			key = alias
+		}
+	}
+	return recursed, key, nil
+}
+
+// deprecationReporter calls the function from WithDeprecatedKeyFunc
+// once for each use of an alias
+type deprecationReporter struct {
+	lock     sync.Mutex
+	f        func(DeprecatedKey)
+	reported map[DeprecatedKey]struct{}
+}
+
+func (d *deprecationReporter) report(key DeprecatedKey) {
+	if d == nil {
+		return
+	}
+	d.lock.Lock()
+	if _, ok := d.reported[key]; ok {
+		d.lock.Unlock()
+		return
+	}
+	d.reported[key] = struct{}{}
+	d.lock.Unlock()
+	d.f(key)
+}
+
+// renameSource looks up the key "to" when asked for the key "from"
+type renameSource struct {
+	source nflex.Source
+	from   string
+	to     string
+}
+
+var _ nflex.Source = renameSource{}
+
+func (r renameSource) rename(keys []string) []string {
+	if len(keys) == 0 || keys[0] != r.from {
+		return keys
+	}
+	renamed := make([]string, len(keys))
+	copy(renamed, keys)
+	renamed[0] = r.to
+	return renamed
+}
+
+func (r renameSource) Exists(keys ...string) bool {
+	return r.source.Exists(r.rename(keys)...)
+}
+
+func (r renameSource) GetBool(keys ...string) (bool, error) {
+	return r.source.GetBool(r.rename(keys)...)
+}
+
+func (r renameSource) GetInt(keys ...string) (int64, error) {
+	return r.source.GetInt(r.rename(keys)...)
+}
+
+func (r renameSource) GetFloat(keys ...string) (float64, error) {
+	return r.source.GetFloat(r.rename(keys)...)
+}
+
+func (r renameSource) GetString(keys ...string) (string, error) {
+	return r.source.GetString(r.rename(keys)...)
+}
+
+func (r renameSource) Recurse(keys ...string) nflex.Source {
+	return r.source.Recurse(r.rename(keys)...)
+}
+
+func (r renameSource) Keys(keys ...string) ([]string, error) {
+	return r.source.Keys(r.rename(keys)...)
+}
+
+func (r renameSource) Len(keys ...string) (int, error) {
+	return r.source.Len(r.rename(keys)...)
+}
+
+func (r renameSource) Type(keys ...string) nflex.NodeType {
+	return r.source.Type(r.rename(keys)...)
+}
@@ -0,0 +1,149 @@
+package nfigure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type aliasServer struct {
+	RequestTimeout string `config:"requestTimeout,alias=timeout|req_timeout"`
+	Port           int
+}
+
+type aliasConfig struct {
+	Server aliasServer `config:"server,alias=http"`
+	Hosts  []string    `config:"hosts,alias=servers"`
+}
+
+func TestAliases(t *testing.T) {
+	cases := []struct {
+		name       string
+		files      []string
+		want       aliasConfig
+		deprecated []DeprecatedKey
+	}{
+		{
+			name:  "current names",
+			files: []string{"alias-new.yaml"},
+			want:  aliasConfig{Server: aliasServer{RequestTimeout: "5s", Port: 80}},
+		},
+		{
+			name:  "aliases",
+			files: []string{"alias-old.yaml"},
+			want: aliasConfig{
+				Server: aliasServer{RequestTimeout: "10s"},
+				Hosts:  []string{"a"},
+			},
+			deprecated: []DeprecatedKey{
+				{File: "alias-old.yaml:2:3", Alias: "http", Key: "server"},
+				{File: "alias-old.yaml:2:12", Alias: "http.timeout", Key: "http.requestTimeout"},
+				{File: "alias-old.yaml:4:3", Alias: "servers", Key: "hosts"},
+			},
+		},
+		{
+			name:  "current name wins within a file",
+			files: []string{"alias-both.yaml"},
+			want:  aliasConfig{Server: aliasServer{RequestTimeout: "5s"}},
+			deprecated: []DeprecatedKey{
+				{File: "alias-both.yaml:4:12", Alias: "server.timeout", Key: "server.requestTimeout", Ignored: true},
+				{File: "alias-both.yaml:3:16", Alias: "server.req_timeout", Key: "server.requestTimeout", Ignored: true},
+			},
+		},
+		{
+			name:  "first alias wins within a file",
+			files: []string{"alias-older.yaml"},
+			want:  aliasConfig{Server: aliasServer{RequestTimeout: "7s"}},
+			deprecated: []DeprecatedKey{
+				{File: "alias-older.yaml:3:12", Alias: "server.timeout", Key: "server.requestTimeout"},
+				{File: "alias-older.yaml:2:16", Alias: "server.req_timeout", Key: "server.requestTimeout", Ignored: true},
+			},
+		},
+		{
+			name:  "files are layered",
+			files: []string{"alias-new.yaml", "alias-old.yaml", "alias-hosts.yaml"},
+			want: aliasConfig{
+				Server: aliasServer{RequestTimeout: "5s", Port: 80},
+				Hosts:  []string{"a", "b", "c"},
+			},
+			deprecated: []DeprecatedKey{
+				{File: "alias-old.yaml:2:3", Alias: "http", Key: "server"},
+				{File: "alias-old.yaml:2:12", Alias: "http.timeout", Key: "http.requestTimeout"},
+				{File: "alias-old.yaml:4:3", Alias: "servers", Key: "hosts"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var deprecated []DeprecatedKey
+			registry := NewRegistry(WithFiller("config", NewFileFiller(WithDeprecatedKeyFunc(func(d DeprecatedKey) {
+				deprecated = append(deprecated, d)
+			}))))
+			for _, file := range tc.files {
+				require.NoError(t, registry.ConfigFile(file))
+			}
+			var got aliasConfig
+			require.NoError(t, registry.Request(&got))
+			require.NoError(t, registry.Configure())
+			assert.Equal(t, tc.want, got)
+			assert.ElementsMatch(t, tc.deprecated, deprecated)
+		})
+	}
+}
+
+func TestAliasesWithContent(t *testing.T) {
+	type config struct {
+		Server struct {
+			RequestTimeout string `config:"requestTimeout,alias=timeout"`
+		} `env:"SERVER,content=yaml"`
+	}
+	registry := NewRegistry(WithFiller("env", NewLookupFillerSimple(func(key string) (string, bool) {
+		if key == "SERVER" {
+			return "timeout: 3s", true
+		}
+		return "", false
+	})))
+	var got config
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, "3s", got.Server.RequestTimeout)
+}
+
+func TestAliasReportedOnce(t *testing.T) {
+	type hosts struct {
+		Hosts []string `config:"hosts,alias=servers"`
+	}
+	var deprecated []DeprecatedKey
+	registry := NewRegistry(WithFiller("config", NewFileFiller(WithDeprecatedKeyFunc(func(d DeprecatedKey) {
+		deprecated = append(deprecated, d)
+	}))))
+	require.NoError(t, registry.ConfigFile("alias-old.yaml"))
+	var a, b hosts
+	require.NoError(t, registry.Request(&a))
+	require.NoError(t, registry.Request(&b))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, []string{"a"}, a.Hosts)
+	assert.Equal(t, []string{"a"}, b.Hosts)
+	assert.Equal(t, []DeprecatedKey{{File: "alias-old.yaml:4:3", Alias: "servers", Key: "hosts"}}, deprecated)
+}
+
+func TestAliasInSliceElements(t *testing.T) {
+	var deprecated []DeprecatedKey
+	registry := NewRegistry(WithFiller("config", NewFileFiller(WithDeprecatedKeyFunc(func(d DeprecatedKey) {
+		deprecated = append(deprecated, d)
+	}))))
+	require.NoError(t, registry.ConfigFile("alias-list1.yaml"))
+	require.NoError(t, registry.ConfigFile("alias-list2.yaml"))
+	var got struct {
+		Servers []struct {
+			RequestTimeout string `config:"requestTimeout,alias=timeout"`
+		} `config:"servers"`
+	}
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	require.Len(t, got.Servers, 2)
+	assert.Equal(t, "1s", got.Servers[0].RequestTimeout)
+	assert.Equal(t, "2s", got.Servers[1].RequestTimeout)
+	assert.Equal(t, []DeprecatedKey{{File: "alias-list2.yaml:2:14", Alias: "servers.0.timeout", Key: "servers.0.requestTimeout"}}, deprecated)
+}
@@ -0,0 +1,101 @@
+package nfigure
+
+import (
+	"reflect"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nfigure/internal/pointer"
+	"github.com/muir/nflex"
+	"github.com/muir/reflectutils"
+	"github.com/pkg/errors"
+)
+
+// contentFiller is for fillers whose values can be documents, like
+// JSON, rather than simple strings.  The "content" option selects the
+// format.  Documents are filled the same way that configuration files
+// are filled.
+type contentFiller interface {
+	Filler
+	content(t reflect.Type, tag reflectutils.Tag) (nflex.Source, bool, error)
+}
+
+var contentDecoders = map[string]func([]byte) (nflex.Source, error){
+	"json":               nflex.UnmarshalJSON,
+	"application/json":   nflex.UnmarshalJSON,
+	"yaml":               nflex.UnmarshalYAML,
+	"application/yaml":   nflex.UnmarshalYAML,
+	"application/x-yaml": nflex.UnmarshalYAML,
+}
+
+func checkContent(format string) error {
+	if _, ok := contentDecoders[format]; !ok {
+		return commonerrors.ProgrammerError(errors.Errorf("content=%s is not supported, use content=json|yaml", format))
+	}
+	return nil
+}
+
+// decodeContent parses a document.  The supported formats are
+// "json" and "yaml".
+func decodeContent(format string, value string) (nflex.Source, error) {
+	if err := checkContent(format); err != nil {
+		return nil, err
+	}
+	return contentDecoders[format]([]byte(value))
+}
+
+// withContent decodes the documents provided by contentFillers for the
+// field being filled.  The documents are combined with the configuration
+// files (ahead of them) so that the field and everything within it is
+// filled as if the documents were part of the configuration files.
+func (x fillData) withContent(t reflect.Type) (fillData, error) {
+	var sources []nflex.Source
+	var origins []fileOrigin
+	for _, fp := range x.fillers.pairs(x.tags, x.meta) {
+		cf, ok := fp.Filler.(contentFiller)
+		if !ok || fp.Tag.Tag == "" {
+			continue
+		}
+		source, ok, err := cf.content(t, fp.Tag)
+		if err != nil {
+			return x, errors.Wrapf(err, "fill %s using %s", x.name, fp.Tag.Tag)
+		}
+		if ok {
+			sources = append(sources, source)
+			origins = append(origins, fileOrigin{name: describeSource(fp, t, pointer.Value(x.meta.First)), source: source})
+		}
+	}
+	if len(sources) == 0 {
+		return x, nil
+	}
+	tag := x.r.configTag
+	fileFiller := NewFileFiller()
+	if existing, ok := x.fillers.m[tag].(FileFiller); ok {
+		fileFiller = existing
+		sources = append(sources, existing.source)
+	}
+	if !pointer.Value(x.meta.First) {
+		for i, j := 0, len(sources)-1; i < j; i, j = i+1, j-1 {
+			sources[i], sources[j] = sources[j], sources[i]
+		}
+		for i, j := 0, len(origins)-1; i < j; i, j = i+1, j-1 {
+			origins[i], origins[j] = origins[j], origins[i]
+		}
+		origins = append(append([]fileOrigin{}, fileFiller.origins...), origins...)
+	} else {
+		origins = append(origins, fileFiller.origins...)
+	}
+	fileFiller.source = nflex.CombineSources(sources...)
+	fileFiller.origins = origins
+	x.fillers = x.fillers.Copy().Build(tag, fileFiller)
+	return x, nil
+}
+
+// configTag returns the tag used by the FileFiller in fillers
+func configTag(fillers *fillerCollection) string {
+	for _, tag := range fillers.Order() {
+		if _, ok := fillers.m[tag].(FileFiller); ok {
+			return tag
+		}
+	}
+	return "config"
+}
@@ -0,0 +1,105 @@
+package nfigure
+
+import (
+	"testing"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nflex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contentDB struct {
+	Host string   `config:"hostname"`
+	Port int      `config:"port" default:"5432"`
+	Tags []string `config:"tags"`
+}
+
+type contentConfig struct {
+	DB     contentDB      `flag:"db,content=json"`
+	Env    contentDB      `env:"CONTENT_DB,content=yaml"`
+	Ptr    *contentDB     `flag:"ptr,content=json"`
+	Limits map[string]int `flag:"limits,content=yaml"`
+	Level  int            `flag:"level,content=json"`
+}
+
+func TestContent(t *testing.T) {
+	cases := []struct {
+		name  string
+		args  []string
+		env   string
+		want  contentConfig
+		error string
+		check func(error) bool
+	}{
+		{
+			name: "documents",
+			args: []string{"--db", `{"hostname": "flaghost", "tags": ["a"]}`, "--ptr", `{"port": 7}`, "--limits", "{x: 1, y: 2}", "--level", "3"},
+			env:  "hostname: envhost\nport: 9\n",
+			want: contentConfig{
+				DB:     contentDB{Host: "flaghost", Port: 5432, Tags: []string{"a", "f"}},
+				Env:    contentDB{Host: "envhost", Port: 9},
+				Ptr:    &contentDB{Port: 7},
+				Limits: map[string]int{"x": 1, "y": 2},
+				Level:  3,
+			},
+		},
+		{
+			name: "no documents",
+			want: contentConfig{
+				DB:  contentDB{Host: "filehost", Port: 5432, Tags: []string{"f"}},
+				Env: contentDB{Port: 5432},
+				Ptr: &contentDB{Port: 5432}, // from the default
+			},
+		},
+		{
+			name:  "bad flag",
+			args:  []string{"--db", `{"hostname":`},
+			error: "--db",
+			check: commonerrors.IsUsageError,
+		},
+		{
+			name:  "bad env",
+			env:   "hostname: [",
+			error: "CONTENT_DB",
+			check: commonerrors.IsEnvironmentError,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.env != "" {
+				t.Setenv("CONTENT_DB", tc.env)
+			}
+			fh := PosixFlagHandler(WithArgs(tc.args))
+			registry := NewRegistry(
+				WithFiller("flag", fh),
+				WithFiller("config", NewFileFiller(WithUnmarshalOpts(nflex.WithFS(content)))))
+			require.NoError(t, registry.ConfigFile("source8.yaml"))
+			var got contentConfig
+			require.NoError(t, registry.Request(&got))
+			err := registry.Configure()
+			if tc.error != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.error)
+					assert.True(t, tc.check(err), "error type")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestContentUnsupported(t *testing.T) {
+	var model struct {
+		X contentDB `flag:"x,content=xml"`
+	}
+	registry := NewRegistry(WithFiller("flag", PosixFlagHandler(WithArgs(nil))))
+	require.NoError(t, registry.Request(&model))
+	err := registry.Configure()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "content=xml is not supported")
+		assert.True(t, commonerrors.IsProgrammerError(err), "programmer error")
+	}
+}
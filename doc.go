@@ -11,6 +11,10 @@ files.
 
 Once that's done, call Configure() to actually fill the structs.
 
+Structs, at any depth, that implement Defaulter have SetDefaults() called before
+they are filled.  Structs that implement Validator have Validate() called after
+everything is filled.
+
 For file fillers (filling from a configuration file), all data elements
 that are exported will be filled if there is a matching element in a
 configuration file.  Disable filling an element by overriding its fill
@@ -73,18 +77,27 @@ Each filler defines it's own parameters.  Here's a summary:
 	NewEnvFiller(), environment variables, "env":
 	name (defaults to "-")
 	split: how to split strings into array/slice elements
+	enum: the allowed values, eg: enum=debug|info|warn
+	content: the value is a document, "json" or "yaml", filled like a config file
 
 	NewFileFiller(), fill from config files, "config":
 	name (defaults to exported field name)
+	enum: the allowed values
 
 	PosixFlagHandler()/GoFlagHandler, fill from the command line:
-	name
+	name (on a struct field: a prefix for the flags within)
 	split: how to split strings into array/slice/map key/value elements
 		special values: explode, quote, space, comma, equal, equals, none
 	map: how to treat maps, values are "explode" or "prefix"
 	counter: is this a numeric counter (eg: foo -v -v -v)
 	required: is this a required flag
 	argName: how to describe parameters for this in the usage message
+	enum: the allowed values, shown in the usage message as {a|b|c}
+	hidden: leave the flag out of the usage message
+	deprecated: warn when the flag is used, deprecated=message adds to the warning
+	replacedBy: the values given to a deprecated flag are also given to this flag
+	fromfile: values "@path" and "-" are read from the file or from stdin
+	content: the value is a document, "json" or "yaml", filled like a config file
 
 Ultimately setting variables based on string values is done by
 https://pkg.go.dev/github.com/muir/reflectutils#MakeStringSetter  See the documentation there
@@ -92,8 +105,10 @@ for support for customer types.
 
 Known bugs / limitations:
 
-Combining of arrays, slices, and maps from multiple sources only works if all the
-sources are configuration files.
+Combining of arrays from multiple sources only works if all the sources are
+configuration files.  Slices and maps can be combined from flags, environment
+variables, and configuration files: slice elements are appended in filler order
+and for map keys, the usual first/last precedence applies.
 
 */
 package nfigure
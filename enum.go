@@ -0,0 +1,68 @@
+package nfigure
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/muir/reflectutils"
+	"github.com/pkg/errors"
+)
+
+// enumValues splits an "enum=a|b|c" tag value
+func enumValues(enum string) []string {
+	if enum == "" {
+		return nil
+	}
+	return strings.Split(enum, "|")
+}
+
+// checkEnum verifies that v, which has just been filled, only holds
+// values listed in enum.  Slices and arrays are checked element by
+// element and maps are checked value by value.  The allowed values
+// are converted to the type being checked so that "enum=1|2" works
+// for integers.  Errors are not wrapped with an error class: that is
+// up to the filler.
+func checkEnum(enum string, v reflect.Value) error {
+	if enum == "" {
+		return nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			err := checkEnum(enum, v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			err := checkEnum(enum, iter.Value())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	setter, err := reflectutils.MakeStringSetter(v.Type())
+	if err != nil {
+		return errors.Wrapf(err, "enum for %s", v.Type())
+	}
+	for _, allowed := range enumValues(enum) {
+		a := reflect.New(v.Type()).Elem()
+		if setter(a, allowed) != nil {
+			continue
+		}
+		if reflect.DeepEqual(a.Interface(), v.Interface()) {
+			return nil
+		}
+	}
+	return errors.Errorf("value '%v' is not one of %s", v.Interface(), enum)
+}
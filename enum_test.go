@@ -0,0 +1,113 @@
+package nfigure
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nflex"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnumFlags(t *testing.T) {
+	type options struct {
+		Level  string         `flag:"level,enum=debug|info|warn" help:"log level"`
+		Ports  []int          `flag:"port,enum=80|443"`
+		Labels map[string]int `flag:"label,enum=1|2"`
+	}
+	cases := []struct {
+		cmd   string
+		want  options
+		error string
+	}{
+		{
+			cmd:  "--level info --port 443 --label x=2",
+			want: options{Level: "info", Ports: []int{443}, Labels: map[string]int{"x": 2}},
+		},
+		{
+			cmd:   "--level trace",
+			error: "--level: value 'trace' is not one of debug|info|warn",
+		},
+		{
+			cmd:   "--port 80 --port 8080",
+			error: "--port: value '8080' is not one of 80|443",
+		},
+		{
+			cmd:   "--label x=3",
+			error: "--label: value '3' is not one of 1|2",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.cmd, func(t *testing.T) {
+			var got options
+			fh := PosixFlagHandler(WithArgs(strings.Split(tc.cmd, " ")))
+			registry := NewRegistry(WithFiller("flag", fh))
+			require.NoError(t, registry.Request(&got))
+			err := registry.Configure()
+			if tc.error != "" {
+				require.Error(t, err)
+				assert.True(t, commonerrors.IsUsageError(err), "usage error")
+				assert.Contains(t, err.Error(), tc.error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			usage := fh.Usage()
+			assert.Contains(t, usage, "[--level={debug|info|warn}]")
+			assert.Contains(t, usage, "[--port={80|443},{80|443}...]")
+			assert.Contains(t, usage, "[--label key={1|2}]")
+		})
+	}
+}
+
+func TestEnumEnv(t *testing.T) {
+	var testData struct {
+		Mode string `env:"ENUM_MODE,enum=fast|slow"`
+	}
+	require.NoError(t, os.Setenv("ENUM_MODE", "slow"), "set ENUM_MODE")
+	registry := NewRegistry()
+	require.NoError(t, registry.Request(&testData), "add model")
+	require.NoError(t, registry.Configure(), "configure")
+	assert.Equal(t, "slow", testData.Mode)
+
+	require.NoError(t, os.Setenv("ENUM_MODE", "medium"), "set ENUM_MODE")
+	registry = NewRegistry()
+	require.NoError(t, registry.Request(&testData), "add model")
+	err := registry.Configure()
+	require.Error(t, err, "configure")
+	assert.True(t, commonerrors.IsEnvironmentError(err), "environment error")
+	assert.Contains(t, err.Error(), "value 'medium' is not one of fast|slow")
+}
+
+func TestEnumFile(t *testing.T) {
+	newRegistry := func() *Registry {
+		registry := NewRegistry(
+			WithoutFillers(),
+			WithFiller("nf", NewFileFiller(WithUnmarshalOpts(nflex.WithFS(content)))),
+			WithMetaTag("nf"),
+			WithFiller("nfigure", nil))
+		require.NoError(t, registry.ConfigFile("source.yaml"), "add source.yaml")
+		return registry
+	}
+
+	var good struct {
+		II int `nf:"II,enum=10|20"`
+	}
+	registry := newRegistry()
+	require.NoError(t, registry.Request(&good), "add model")
+	require.NoError(t, registry.Configure(), "configure")
+	assert.Equal(t, 10, good.II)
+
+	var bad struct {
+		II int `nf:"II,enum=11|20"`
+	}
+	registry = newRegistry()
+	require.NoError(t, registry.Request(&bad), "add model")
+	err := registry.Configure()
+	require.Error(t, err, "configure")
+	assert.True(t, commonerrors.IsConfigurationError(err), "configuration error")
+	assert.Contains(t, err.Error(), "value '10' is not one of 11|20")
+}
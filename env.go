@@ -3,8 +3,11 @@ package nfigure
 import (
 	"os"
 	"reflect"
+	"sort"
+	"strconv"
 
 	"github.com/muir/commonerrors"
+	"github.com/muir/nflex"
 	"github.com/muir/reflectutils"
 	"github.com/pkg/errors"
 )
@@ -14,9 +17,17 @@ import (
 type LookupFiller struct {
 	lookup    func(value string, tag string) (string, bool, error)
 	wrapError func(error) error
+	describe  func(key string) string
+	isEnv     bool // from NewEnvFiller, used to label usage messages
 }
 
-var _ CanLenFiller = LookupFiller{}
+var (
+	_ CanLenFiller    = LookupFiller{}
+	_ CanKeysFiller   = LookupFiller{}
+	_ combiningFiller = LookupFiller{}
+	_ contentFiller   = LookupFiller{}
+	_ sourceDescriber = LookupFiller{}
+)
 
 // LookupFillerOpt are options for creating LookupFillers
 type LookupFillerOpt func(*LookupFiller)
@@ -35,6 +46,12 @@ type LookupFillerOpt func(*LookupFiller)
 // "JSON" tag that specifies that the the string should be decoded as JSON. This
 // overrides other decoding possibilities.
 //
+// "enum" tag that restricts the allowed values, eg: "enum=debug|info|warn".
+//
+// "content" tag that specifies that the value is a "json" or "yaml" document.
+// Unlike "JSON", the document is filled the same way that configuration
+// files are: names come from the "config" tags and "default" tags still apply.
+//
 //	type SubStruct struct {
 //		Foo	     int  `json:"foo"`
 //	}
@@ -46,8 +63,11 @@ type LookupFillerOpt func(*LookupFiller)
 //	}
 func NewEnvFiller(opts ...LookupFillerOpt) Filler {
 	return NewLookupFillerSimple(os.LookupEnv,
-		append([]LookupFillerOpt{WrapLookupErrors(commonerrors.EnvironmentError)},
-			opts...)...)
+		append([]LookupFillerOpt{
+			WrapLookupErrors(commonerrors.EnvironmentError),
+			DescribeLookups(func(key string) string { return "$" + key }),
+			func(e *LookupFiller) { e.isEnv = true },
+		}, opts...)...)
 }
 
 // NewDefaultFiller creates a LookupFiller that simply fills in the value provided
@@ -105,10 +125,32 @@ func WrapLookupErrors(f func(error) error) LookupFillerOpt {
 	}
 }
 
+// DescribeLookups sets how LookupFillers name where a value came from
+// in error messages.  NewEnvFiller names environment variables "$NAME".
+// Without DescribeLookups, the struct tag is used, like `secret:"dbpasswd"`.
+func DescribeLookups(f func(key string) string) LookupFillerOpt {
+	return func(e *LookupFiller) {
+		e.describe = f
+	}
+}
+
+func (e LookupFiller) describeSource(t reflect.Type, tag reflectutils.Tag, firstFirst bool) string {
+	var tagData envTag
+	if tag.Fill(&tagData) != nil {
+		return ""
+	}
+	if e.describe != nil {
+		return e.describe(tagData.Variable)
+	}
+	return tag.Tag + ":" + strconv.Quote(tagData.Variable)
+}
+
 type envTag struct {
 	Variable string `pt:"0"`
 	Split    string `pt:"split"`
 	JSON     bool   `pt:"JSON"`
+	Enum     string `pt:"enum"`
+	Content  string `pt:"content"`
 }
 
 // Fill is part of the Filler contract.  It is used by Registry.Configure.
@@ -119,7 +161,7 @@ func (e LookupFiller) Fill(
 	firstFirst bool,
 	combineObjects bool,
 ) (bool, error) {
-	if tag.Tag == "" {
+	if tag.Tag == "" || combines(t, combineObjects) {
 		return false, nil
 	}
 	var tagData envTag
@@ -127,7 +169,7 @@ func (e LookupFiller) Fill(
 	if err != nil {
 		return false, commonerrors.ProgrammerError(errors.Wrapf(err, "%s tag", tag.Tag))
 	}
-	if tagData.Variable == "" {
+	if tagData.Variable == "" || tagData.Content != "" {
 		return false, nil
 	}
 	value, ok, err := e.lookup(tagData.Variable, tag.Value)
@@ -152,41 +194,102 @@ func (e LookupFiller) Fill(
 	if err != nil {
 		return false, e.wrapError(errors.Wrapf(err, "%s tag", tag.Tag))
 	}
+	err = checkEnum(tagData.Enum, v)
+	if err != nil {
+		return false, e.wrapError(errors.Wrapf(err, "%s %s", tag.Tag, tagData.Variable))
+	}
 	return true, nil
 }
 
-// Len is part of the Filler contract
+func (e LookupFiller) content(t reflect.Type, tag reflectutils.Tag) (nflex.Source, bool, error) {
+	var tagData envTag
+	err := tag.Fill(&tagData)
+	if err != nil {
+		return nil, false, commonerrors.ProgrammerError(errors.Wrapf(err, "%s tag", tag.Tag))
+	}
+	if tagData.Variable == "" || tagData.Content == "" {
+		return nil, false, nil
+	}
+	value, ok, err := e.lookup(tagData.Variable, tag.Value)
+	if err != nil {
+		return nil, false, commonerrors.ProgrammerError(errors.Wrapf(err, tag.Tag))
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	source, err := decodeContent(tagData.Content, value)
+	if err != nil {
+		if commonerrors.IsProgrammerError(err) {
+			return nil, false, errors.Wrapf(err, "%s tag", tag.Tag)
+		}
+		return nil, false, e.wrapError(errors.Wrapf(err, "%s %s", tag.Tag, tagData.Variable))
+	}
+	return source, true, nil
+}
+
+func (e LookupFiller) fillWhole(t reflect.Type, tag reflectutils.Tag) (reflect.Value, bool, error) {
+	return fillTemporary(t, func(v reflect.Value) (bool, error) {
+		return e.Fill(t, v, tag, true, false)
+	})
+}
+
+// Len is part of the CanLenFiller contract
 func (e LookupFiller) Len(
 	t reflect.Type,
 	tag reflectutils.Tag,
 	firstFirst bool,
 	combineObjects bool,
 ) (int, bool) {
-	return lenThroughFill(e, t, tag, firstFirst, combineObjects)
+	return lenThroughFill(e, t, tag)
 }
 
-func lenThroughFill(
-	f Filler,
+// Keys is part of the CanKeysFiller contract
+func (e LookupFiller) Keys(
 	t reflect.Type,
 	tag reflectutils.Tag,
 	firstFirst bool,
 	combineObjects bool,
-) (int, bool) {
+) ([]string, bool) {
+	return keysThroughFill(e, t, tag)
+}
+
+func lenThroughFill(f combiningFiller, t reflect.Type, tag reflectutils.Tag) (int, bool) {
 	switch reflectutils.NonPointer(t).Kind() {
 	case reflect.Array, reflect.Slice:
 	default:
 		return 0, false
 	}
-	v := reflect.New(t).Elem()
-	filled, err := f.Fill(t, v, tag, firstFirst, combineObjects)
-	if err != nil {
-		return 0, false
-	}
-	if !filled {
+	v, filled, err := f.fillWhole(t, tag)
+	if err != nil || !filled {
 		return 0, false
 	}
 	for v.Type().Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
 		v = v.Elem()
 	}
 	return v.Len(), true
 }
+
+func keysThroughFill(f combiningFiller, t reflect.Type, tag reflectutils.Tag) ([]string, bool) {
+	if reflectutils.NonPointer(t).Kind() != reflect.Map {
+		return nil, false
+	}
+	v, filled, err := f.fillWhole(t, tag)
+	if err != nil || !filled {
+		return nil, false
+	}
+	for v.Type().Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, keyString(k))
+	}
+	sort.Strings(keys)
+	return keys, true
+}
@@ -0,0 +1,77 @@
+package nfigure
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+type docsOptions struct {
+	Verbose bool   `flag:"v verbose" help:"print more"`
+	Config  string `flag:"config,argName=file" help:"configuration file"`
+}
+
+type docsRemoveOptions struct {
+	Force bool `flag:"f force" help:"do not ask"`
+}
+
+// ExampleGenerateDocs is the body of a program that "go generate" runs
+// to write reference documentation, like:
+//
+//	//go:generate go run ./gendocs -man ../docs/man -markdown ../docs/reference
+func ExampleGenerateDocs() {
+	fh := PosixFlagHandler()
+	_, err := fh.AddSubcommand("remove", "remove files", &docsRemoveOptions{}, SubcommandAliases("rm"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dir, err := os.MkdirTemp("", "gendocs")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = GenerateDocs(fh, []string{"-man", dir, "-markdown", dir},
+		DocProgram("myapp"),
+		DocModels(&docsOptions{}))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, entry := range entries {
+		fmt.Println(entry.Name())
+	}
+	markdown, err := os.ReadFile(filepath.Join(dir, "myapp-remove.md"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(string(markdown))
+	// Output:
+	// myapp-remove.1
+	// myapp-remove.md
+	// myapp.1
+	// myapp.md
+	// # myapp remove
+	//
+	// remove files
+	//
+	// ## Synopsis
+	//
+	// ```
+	// myapp remove [-flags] [parameters]
+	// ```
+	//
+	// ## Options
+	//
+	// - `[-f]` `[--[no-]force]`: do not ask
+	//
+	// ## See also
+	//
+	// - [myapp](myapp.md)
+}
@@ -1,11 +1,15 @@
 package nfigure
 
 import (
+	"encoding"
+	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 
 	"github.com/muir/commonerrors"
 	"github.com/muir/nfigure/internal/pointer"
+	"github.com/muir/nflex"
 	"github.com/muir/reflectutils"
 	"github.com/pkg/errors"
 )
@@ -79,9 +83,33 @@ type CanAddConfigFileFiller interface {
 	AddConfigFile(file string, keyPath []string) (Filler, error)
 }
 
+// sourceDescriber is implemented by fillers that can name where a value
+// came from in terms that the user controls, like "$DB_PORT", "--db-port",
+// or "config.yaml: db.port".
+type sourceDescriber interface {
+	describeSource(t reflect.Type, tag reflectutils.Tag, firstFirst bool) string
+}
+
+// describeSource returns where a value filled by fp came from, if known
+func describeSource(fp fillPair, t reflect.Type, firstFirst bool) string {
+	if d, ok := fp.Filler.(sourceDescriber); ok {
+		return d.describeSource(t, fp.Tag, firstFirst)
+	}
+	return ""
+}
+
+// CanAddConfigSourceFiller indicates AddConfigSource is supported
+type CanAddConfigSourceFiller interface {
+	Filler
+	// AddConfigSource is like AddConfigFile for data that has already
+	// been parsed.
+	AddConfigSource(source nflex.Source, keyPath []string) (Filler, error)
+}
+
 type fillData struct {
 	r       *Request
 	name    string
+	path    string // Go path to the field being filled, used for provenance
 	tags    reflectutils.TagSet
 	meta    metaFields
 	fillers *fillerCollection
@@ -104,25 +132,42 @@ type metaFields struct {
 func (f *fillerCollection) Len(
 	t reflect.Type,
 	x fillData,
-) (int, func() (*fillerCollection, error)) {
+) (int, func() (*fillerCollection, error), error) {
 	var total int
 	pairs := f.pairs(x.tags, x.meta)
 	debugf("fill: Len: %s pairs: %+v", x.name, pairs)
 	lengths := make([]int, len(pairs))
+	wholes := make([]reflect.Value, len(pairs))
 	combine := pointer.Value(x.meta.Combine)
 	first := pointer.Value(x.meta.First)
 	for i, fp := range pairs {
-		canLen, ok := fp.Filler.(CanLenFiller)
-		if !ok {
-			continue
-		}
-		length, ok := canLen.Len(t, fp.Tag, first, combine)
-		if !ok {
-			continue
+		var length int
+		if whole, ok := fp.Filler.(combiningFiller); ok {
+			v, ok, err := whole.fillWhole(t, fp.Tag)
+			if err != nil {
+				return 0, nil, errors.Wrapf(err, "fill %s using %s", x.name, fp.Tag.Tag)
+			}
+			if !ok {
+				continue
+			}
+			wholes[i] = v
+			length = v.Len()
+		} else {
+			canLen, ok := fp.Filler.(CanLenFiller)
+			if !ok {
+				continue
+			}
+			length, ok = canLen.Len(t, fp.Tag, first, combine)
+			if !ok {
+				continue
+			}
 		}
 		debugf("fill: Len: filler %s: %d for %s", fp.ForcedTag, length, x.name)
 		lengths[i] = length
 		total += length
+		if length > 0 {
+			x.noteContribution(fp)
+		}
 		if !combine {
 			break
 		}
@@ -136,9 +181,18 @@ func (f *fillerCollection) Len(
 		}
 		key := pairs[index].ForcedTag
 		debugf("fill: Len: recurse %s filler %s (%s), %d:%d/%d items", key, x.name, t, index, done, lengths[index])
-		filler, err := simpleRecurseFiller(pairs[index].Filler, strconv.Itoa(done))
-		if err != nil {
-			return nil, err
+		var filler Filler
+		if wholes[index].IsValid() {
+			filler = valueFiller{
+				v:      wholes[index].Index(done),
+				source: describeSource(pairs[index], t, first),
+			}
+		} else {
+			var err error
+			filler, err = simpleRecurseFiller(pairs[index].Filler, strconv.Itoa(done))
+			if err != nil {
+				return nil, err
+			}
 		}
 		done++
 		if done >= lengths[index] {
@@ -149,25 +203,52 @@ func (f *fillerCollection) Len(
 			m:     map[string]Filler{key: filler},
 			order: []string{key},
 		}, nil
-	}
+	}, nil
 }
 
-func (f *fillerCollection) Keys(t reflect.Type, tagSet reflectutils.TagSet, meta metaFields) []string {
+// Keys returns the keys for a map from all the fillers.  The fillerCollection
+// that is returned should be used to fill the values.
+func (f *fillerCollection) Keys(t reflect.Type, x fillData) ([]string, *fillerCollection, error) {
 	var all []string
 	seen := make(map[string]struct{})
-	first := pointer.Value(meta.First)
-	combine := pointer.Value(meta.Combine)
-	for _, fp := range f.pairs(tagSet, meta) {
-		canKey, ok := fp.Filler.(CanKeysFiller)
-		if !ok {
-			continue
+	first := pointer.Value(x.meta.First)
+	combine := pointer.Value(x.meta.Combine)
+	fillers := f.Copy()
+	for _, fp := range f.pairs(x.tags, x.meta) {
+		var keys []string
+		if whole, ok := fp.Filler.(combiningFiller); ok {
+			v, ok, err := whole.fillWhole(t, fp.Tag)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "fill %s using %s", x.name, fp.Tag.Tag)
+			}
+			if !ok {
+				fillers.Remove(fp.ForcedTag)
+				continue
+			}
+			values := make(mapValuesFiller)
+			source := describeSource(fp, t, first)
+			for _, k := range v.MapKeys() {
+				key := keyString(k)
+				values[key] = valueFiller{v: v.MapIndex(k), source: source}
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			fillers.Add(fp.ForcedTag, values)
+		} else {
+			canKey, ok := fp.Filler.(CanKeysFiller)
+			if !ok {
+				continue
+			}
+			keys, ok = canKey.Keys(t, fp.Tag, first, combine)
+			if !ok {
+				continue
+			}
 		}
-		keys, ok := canKey.Keys(t, fp.Tag, first, combine)
-		if !ok {
-			continue
+		if len(keys) > 0 {
+			x.noteContribution(fp)
 		}
 		if !combine {
-			return keys
+			return keys, fillers, nil
 		}
 		for _, key := range keys {
 			if _, ok := seen[key]; !ok {
@@ -176,7 +257,101 @@ func (f *fillerCollection) Keys(t reflect.Type, tagSet reflectutils.TagSet, meta
 			}
 		}
 	}
-	return all
+	return all, fillers, nil
+}
+
+// noteContribution records the provenance of an array, slice, or map that
+// is filled element by element so that the value as a whole has a filler.
+// The first filler to contribute is recorded.
+func (x fillData) noteContribution(fp fillPair) {
+	if _, ok := x.r.provenance[x.path]; !ok {
+		x.r.provenance[x.path] = fp.ForcedTag
+	}
+}
+
+// combiningFiller is for fillers, like FlagHandler and LookupFiller, that can
+// only fill slices and maps all at once.  When combining, their Fill leaves
+// slices and maps alone so that Len and Keys can merge what fillWhole provides
+// with the elements from other fillers.
+type combiningFiller interface {
+	Filler
+	fillWhole(t reflect.Type, tag reflectutils.Tag) (reflect.Value, bool, error)
+}
+
+// combines reports if a combiningFiller should leave a value of type t to
+// Len and Keys
+func combines(t reflect.Type, combineObjects bool) bool {
+	if !combineObjects {
+		return false
+	}
+	switch reflectutils.NonPointer(t).Kind() {
+	case reflect.Slice, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+// fillTemporary is used to implement fillWhole
+func fillTemporary(t reflect.Type, fill func(reflect.Value) (bool, error)) (reflect.Value, bool, error) {
+	v := reflect.New(t).Elem()
+	filled, err := fill(v)
+	if err != nil || !filled {
+		return reflect.Value{}, false, err
+	}
+	return v, true, nil
+}
+
+// valueFiller provides a single element of a slice or map that was
+// filled by a combiningFiller.
+type valueFiller struct {
+	v      reflect.Value
+	source string
+}
+
+var (
+	_ CanRecurseFiller = valueFiller{}
+	_ sourceDescriber  = valueFiller{}
+)
+
+func (f valueFiller) Fill(t reflect.Type, v reflect.Value, _ reflectutils.Tag, _ bool, _ bool) (bool, error) {
+	if t != f.v.Type() {
+		return false, nil
+	}
+	v.Set(f.v)
+	return true, nil
+}
+
+func (f valueFiller) Recurse(string) (Filler, error) { return nil, nil }
+
+func (f valueFiller) describeSource(reflect.Type, reflectutils.Tag, bool) string { return f.source }
+
+// mapValuesFiller provides the values of a map that was filled by a
+// combiningFiller.  The keys are formatted with keyString.
+type mapValuesFiller map[string]valueFiller
+
+var _ CanRecurseFiller = mapValuesFiller{}
+
+func (f mapValuesFiller) Fill(reflect.Type, reflect.Value, reflectutils.Tag, bool, bool) (bool, error) {
+	return false, nil
+}
+
+func (f mapValuesFiller) Recurse(key string) (Filler, error) {
+	v, ok := f[key]
+	if !ok {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func keyString(k reflect.Value) string {
+	if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(k.Interface())
 }
 
 func (r *Request) fill() error {
@@ -186,7 +361,10 @@ func (r *Request) fill() error {
 		r.metaTag = r.registry.metaTag
 	}
 	debug("fill: start fill", t)
+	r.provenance = make(map[string]string)
+	r.sources = make(map[string]string)
 	fillers := r.getFillers()
+	r.configTag = configTag(fillers)
 	for _, p := range r.getPrefix() {
 		debug("fill: recurse for prefix", p, "from", callers(3))
 		var err error
@@ -195,19 +373,21 @@ func (r *Request) fill() error {
 			return commonerrors.ConfigurationError(errors.Wrap(err, "request prefix "+p))
 		}
 	}
+	setDefaults(v)
 	_, err := fillData{
 		r:       r,
 		name:    "",
 		tags:    reflectutils.TagSet{},
 		fillers: fillers,
 	}.fillStruct(t, v)
-	if validator, ok := r.getValidator(); ok {
-		err := validator.Struct(r.object)
-		if err != nil {
-			return commonerrors.ValidationError(errors.Wrap(err, t.String()))
-		}
+	if err != nil {
+		return err
 	}
-	return err
+	err = validateHooks("", v)
+	if err != nil {
+		return err
+	}
+	return r.validate(t, v)
 }
 
 func (x fillData) fillStruct(t reflect.Type, v reflect.Value) (bool, error) {
@@ -235,6 +415,7 @@ func (x fillData) fillStruct(t reflect.Type, v reflect.Value) (bool, error) {
 		filled, err := fillData{
 			r:       x.r,
 			name:    f.Name,
+			path:    joinPath(x.path, f.Name),
 			tags:    tags,
 			meta:    meta,
 			fillers: x.fillers,
@@ -257,7 +438,7 @@ func (f *fillerCollection) Recurse(name string, t reflect.Type, tagSet reflectut
 	f = f.Copy()
 	for tagName, filler := range f.m {
 		tag := tagSet.Get(tagName)
-		recursed, err := recurseFiller(filler, name, tag)
+		recursed, err := recurseFiller(filler, name, t, tag)
 		debug("fill: Recurse", name, t, tagName, tag)
 		if err != nil {
 			return nil, errors.Wrap(err, tagName)
@@ -285,6 +466,10 @@ func (x fillData) recurseFillField(t reflect.Type, v reflect.Value) (bool, error
 	if err != nil {
 		return false, err
 	}
+	x, err = x.withContent(t)
+	if err != nil {
+		return false, err
+	}
 	return x.fillField(t, v)
 }
 
@@ -297,6 +482,12 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 		isStructural = true
 	}
 
+	if t.Kind() == reflect.Struct {
+		// before the fillers so that a struct filled as a whole
+		// keeps what was filled
+		setDefaults(v)
+	}
+
 	var anyFilled bool
 	combine := pointer.Value(x.meta.Combine)
 	first := pointer.Value(x.meta.First)
@@ -309,6 +500,10 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 		}
 		if filled {
 			x.fillers.Remove(fp.Tag.Tag)
+			x.r.provenance[x.path] = fp.ForcedTag
+			if source := describeSource(fp, t, first); source != "" {
+				x.r.sources[x.path] = source
+			}
 			anyFilled = true
 			if isStructural && combine {
 				continue
@@ -320,6 +515,10 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 	if anyFilled && x.meta.Desc != nil && !*x.meta.Desc {
 		return true, nil
 	}
+	if anyFilled && isStructural && !combine {
+		// descending would add elements from other fillers
+		return true, nil
+	}
 
 	switch t.Kind() {
 	case reflect.Struct:
@@ -333,13 +532,17 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 			return false, err
 		}
 		if !filled {
-			return false, nil
+			return anyFilled, nil
 		}
 		v.Set(e)
 		return true, nil
 	case reflect.Array:
-		count, recurseInSequence := x.fillers.Len(t, x)
+		count, recurseInSequence, err := x.fillers.Len(t, x)
+		if err != nil {
+			return false, err
+		}
 		cap := v.Len()
+		basePath := x.path
 		elemType := t.Elem()
 		for i := 0; i < count && i < cap; i++ {
 			var err error
@@ -347,6 +550,7 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 			if err != nil {
 				return false, err
 			}
+			x.path = indexPath(basePath, strconv.Itoa(i))
 			filled, err := x.fillField(elemType, v.Index(i))
 			if err != nil {
 				return false, err
@@ -357,13 +561,17 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 		}
 		return anyFilled, nil
 	case reflect.Slice:
-		count, recurseInSequence := x.fillers.Len(t, x)
+		count, recurseInSequence, err := x.fillers.Len(t, x)
+		if err != nil {
+			return false, err
+		}
 		if count == 0 {
-			return false, nil
+			return anyFilled, nil
 		}
 		var a reflect.Value
 		a = reflect.MakeSlice(t, count, count)
 		elemType := t.Elem()
+		basePath := x.path
 		for i := 0; i < count; i++ {
 			var err error
 			x.fillers, err = recurseInSequence()
@@ -371,6 +579,7 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 				return false, err
 			}
 			debugf("fill slice element %d, name = %s\n", i, x.name)
+			x.path = indexPath(basePath, strconv.Itoa(i))
 			filled, err := x.fillField(elemType, a.Index(i))
 			if err != nil {
 				return false, err
@@ -386,7 +595,10 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 		}
 		return anyFilled, nil
 	case reflect.Map:
-		keys := x.fillers.Keys(t, x.tags, x.meta)
+		keys, fillers, err := x.fillers.Keys(t, x)
+		if err != nil {
+			return false, err
+		}
 		if len(keys) == 0 {
 			return anyFilled, nil
 		}
@@ -401,8 +613,8 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 		if err != nil {
 			return false, commonerrors.ProgrammerError(errors.Wrapf(err, "set key for %T", t))
 		}
-		fillers := x.fillers.Copy()
 		elemType := t.Elem()
+		basePath := x.path
 		for _, key := range keys {
 			kp := reflect.New(t.Key())
 			err := f(kp.Elem(), key)
@@ -415,6 +627,7 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 			if err != nil {
 				return false, err
 			}
+			x.path = indexPath(basePath, key)
 			filled, err := x.fillField(elemType, vp.Elem())
 			if err != nil {
 				return false, errors.Wrap(err, "set value")
@@ -430,7 +643,17 @@ func (x fillData) fillField(t reflect.Type, v reflect.Value) (bool, error) {
 	}
 }
 
-func recurseFiller(filler Filler, name string, tag reflectutils.Tag) (Filler, error) {
+// recurseFieldFiller is for fillers that need to know more than
+// the name when recursing into a struct field.
+type recurseFieldFiller interface {
+	CanRecurseFiller
+	recurseField(name string, t reflect.Type, tag reflectutils.Tag) (Filler, error)
+}
+
+func recurseFiller(filler Filler, name string, t reflect.Type, tag reflectutils.Tag) (Filler, error) {
+	if canRecurse, ok := filler.(recurseFieldFiller); ok {
+		return canRecurse.recurseField(name, t, tag)
+	}
 	if canRecurse, ok := filler.(CanRecurseFiller); ok {
 		if tag.Tag != "" {
 			var fileTag fileTag
@@ -462,3 +685,14 @@ func simpleRecurseFiller(filler Filler, name string) (Filler, error) {
 	}
 	return filler, nil
 }
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, index string) string {
+	return path + "[" + index + "]"
+}
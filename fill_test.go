@@ -356,3 +356,25 @@ func TestMetaFirstScalar(t *testing.T) {
 		})
 	}
 }
+
+func TestCombineFlagsAndFiles(t *testing.T) {
+	type combined struct {
+		Tags      []string          `flag:"tag" env:"TAGS" config:"tags"`
+		Headers   map[string]string `flag:"header,map=explode,split=equals" config:"headers"`
+		FileFirst map[string]string `config:"headers" flag:"header,map=explode,split=equals"`
+		Single    []string          `flag:"tag" config:"tags" nfigure:",single"`
+	}
+	t.Setenv("TAGS", "e,f")
+	fh := PosixFlagHandler(WithArgs([]string{"--tag", "a", "--tag", "b", "--header", "X=1", "--header", "Z=2"}))
+	registry := NewRegistry(
+		WithFiller("flag", fh),
+		WithFiller("config", NewFileFiller(WithUnmarshalOpts(nflex.WithFS(content)))))
+	require.NoError(t, registry.ConfigFile("source8.yaml"))
+	var got combined
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, []string{"a", "b", "e", "f", "x", "y"}, got.Tags, "tags")
+	assert.Equal(t, map[string]string{"X": "1", "Y": "9", "Z": "2"}, got.Headers, "headers")
+	assert.Equal(t, map[string]string{"X": "0", "Y": "9", "Z": "2"}, got.FileFirst, "file first")
+	assert.Equal(t, []string{"a", "b"}, got.Single, "single")
+}
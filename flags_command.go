@@ -7,12 +7,12 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"text/template"
 	"unicode/utf8"
 
 	"github.com/muir/commonerrors"
 	"github.com/muir/nfigure/internal/pointer"
 	"github.com/muir/nject/v2"
-	"github.com/muir/reflectutils"
 	"github.com/pkg/errors"
 )
 
@@ -71,7 +71,10 @@ var (
 //
 // To indicate that a numeric value is a counter, use "counter".
 //
-// To indicate that a value is required as a flag, use "required".
+// To indicate that a value is required as a flag, use "required".  Once
+// parsing completes, every required flag that was not given (in the top-level
+// command and the selected subcommand) is listed in a single UsageError.
+// Use RequiredSatisfiedBy to allow other fillers to provide the value instead.
 //
 // To tweak the usage message describing the value use "argName=name".
 //
@@ -80,9 +83,67 @@ var (
 //		DebugLevel int `flag:"d,counter"`
 //	}
 //
+// To restrict the values that a flag accepts, use "enum=a|b|c".  The usage
+// message shows the allowed values as "{a|b|c}".
+//
+// To leave a flag out of the usage message, use "hidden".  Hidden flags
+// are still parsed.
+//
+// To mark a flag as deprecated, use "deprecated" or "deprecated=message".
+// Deprecated flags still fill their field, but each use generates a warning
+// (see WarningLogger).  With "replacedBy=name", the values given to the
+// deprecated flag are also given to the flag "name":
+//
+//	struct MyFlags struct {
+//		Timeout    time.Duration `flag:"timeout"`
+//		OldTimeout time.Duration `flag:"tmo,deprecated=use --timeout,replacedBy=timeout,hidden"`
+//	}
+//
+// To read the value of a flag from a file or from stdin, use "fromfile".
+// Then "@path" is replaced by the contents of the file at path and "-" is
+// replaced by the contents of stdin (see WithStdin).  A single trailing newline
+// is removed.  Only one flag can use stdin.  With ResponseFiles, use
+// "--token=@path" so that "@path" is not taken to be a response file.
+//
+//	struct MyFlags struct {
+//		Token string `flag:"token,fromfile"` // --token=@/run/secrets/token
+//		Body  string `flag:"body,fromfile"`  // --body=-
+//	}
+//
+// To give a struct, map, or slice in a single flag, use "content=json" or
+// "content=yaml".  The document is filled the same way that configuration
+// files are: names come from the "config" tags, "default" tags still apply,
+// and values from the document take precedence over values from the
+// configuration files.
+//
+//	type DBConfig struct {
+//		Host string `config:"host"`
+//		Port int    `config:"port" default:"5432"`
+//	}
+//
+//	type MyFlags struct {
+//		DB DBConfig `flag:"db,content=json"` // --db '{"host":"db.local"}'
+//	}
+//
+// A struct field with a flag tag is a flag group: the flags inside
+// it are prefixed with the group's name and a separator (see FlagGroupSeparator).
+// Single-letter flags inside a group become long flags.  Struct fields without
+// a flag tag do not add a prefix.  Pointers to structs are not searched for flags.
+//
+//	type DBFlags struct {
+//		Host string `flag:"host"`
+//	}
+//
+//	type MyFlags struct {
+//		Primary DBFlags `flag:"db"`      // --db-host
+//		Replica DBFlags `flag:"replica"` // --replica-host
+//	}
+//
 // # FlagHandler implements the Filler interface
 //
 // Flags processing ends when a non-flag is encountered or when "--" is found.
+// With PermuteArgs, flags and positional arguments can be intermixed and
+// processing only ends at "--".
 // If it is an error for flags processing to finish while there are still arguments
 // left, use the ExpectNoRemaining option.
 type FlagHandler struct {
@@ -90,10 +151,14 @@ type FlagHandler struct {
 	Parent             *FlagHandler // set only for subcommands
 	subcommands        map[string]*FlagHandler
 	subcommandsOrder   []string
+	subcommandAliases  map[string]string // alias -> subcommand
+	aliases            []string          // for subcommands, from SubcommandAliases
+	helpTopics         []helpTopic
+	firstArg           int // for subcommands, index in args after the subcommand
 	longFlags          map[string]*flagRef
 	shortFlags         map[string]*flagRef
 	mapFlags           map[string]*flagRef // only when map=prefix
-	rawData            []reflect.StructField
+	rawData            []flagField
 	mapRE              *regexp.Regexp
 	remainder          []string
 	onActivate         func(*Registry, *FlagHandler) error
@@ -111,36 +176,66 @@ type FlagHandler struct {
 	debugLogger        fullLogger
 	noPositional       bool
 	args               []string
+	unexpandedArgs     []string // before ResponseFiles expansion
+	argOrigins         []string // response file for each of args
+	responseFileDepth  int
+	requiredRefs       []*flagRef    // in PreWalk order
+	missingRequired    []missingFlag // deferred check, see RequiredSatisfiedBy
 }
 
 var (
 	_ CanPreWalkFiller           = &FlagHandler{}
 	_ CanConfigureCompleteFiller = &FlagHandler{}
 	_ CanPreConfigureFiller      = &FlagHandler{}
+	_ CanLenFiller               = &FlagHandler{}
+	_ CanKeysFiller              = &FlagHandler{}
+	_ combiningFiller            = &FlagHandler{}
+	_ contentFiller              = &FlagHandler{}
+	_ sourceDescriber            = &FlagHandler{}
 )
 
 type fhInheritable struct {
-	tagName        string    //nolint:structcheck
-	registry       *Registry //nolint:structcheck
-	doubleDash     bool
-	singleDash     bool
-	combineShort   bool
-	negativeNo     bool
-	helpTag        string
-	ignorableFlags map[string]bool
+	tagName            string    //nolint:structcheck
+	registry           *Registry //nolint:structcheck
+	doubleDash         bool
+	singleDash         bool
+	combineShort       bool
+	negativeNo         bool
+	helpTag            string
+	ignorableFlags     map[string]bool
+	satisfiedBy        map[string]bool // filler tags that can satisfy "required"
+	warnLogger         Logger
+	suggestDistance    int // for "did you mean"
+	suggestSubcommands bool
+	abbreviations      bool
+	permute            bool
+	usageWidth         int // 0: terminal width, then $COLUMNS, otherwise no wrapping; <0: no wrapping
+	usageTemplate      *template.Template
+	showDefaults       bool // "[default: 8080]" in usage
+	showEnv            bool // "[env: PORT]" in usage
+	showConfig         bool // "[config: port]" in usage
+	groupSeparator     string
+	stdin              *flagStdin // for "fromfile"
 }
 
 type flagTag struct {
-	Name []string `pt:"0,split=space"`
+	Name    []string `pt:"0,split=space"`
+	Section string   `pt:"section"` // usage message section
 	flagTagComparable
 }
 
 type flagTagComparable struct {
-	Map       string `pt:"map"`   // special value: prefix|explode
-	Split     string `pt:"split"` // special value: explode, quote, space, comma, equal, equals, none
-	IsCounter bool   `pt:"counter"`
-	Required  bool   `pt:"required"` // flag must be used
-	ArgName   string `pt:"argName"`  // name of the argument(s) for usage message
+	Map        string `pt:"map"`   // special value: prefix|explode
+	Split      string `pt:"split"` // special value: explode, quote, space, comma, equal, equals, none
+	IsCounter  bool   `pt:"counter"`
+	Required   bool   `pt:"required"`   // flag must be used
+	ArgName    string `pt:"argName"`    // name of the argument(s) for usage message
+	Enum       string `pt:"enum"`       // allowed values, "a|b|c"
+	Hidden     bool   `pt:"hidden"`     // not shown in usage
+	Deprecated string `pt:"deprecated"` // warning message, or "t" for no message
+	ReplacedBy string `pt:"replacedBy"` // values of deprecated flags are forwarded here
+	FromFile   bool   `pt:"fromfile"`   // "@path" and "-" are read from a file or stdin
+	Content    string `pt:"content"`    // json|yaml: the value is a document
 }
 
 type flagRef struct {
@@ -154,6 +249,39 @@ type flagRef struct {
 	tagValue  string
 	imported  *flag.Flag
 	typ       reflect.Type
+	targets   []flagTarget
+	filesRead int // values already handled by readFromFiles
+}
+
+// deprecation returns true if the flag is deprecated, and
+// the message to include in the warning.
+func (ref flagRef) deprecation() (bool, string) {
+	switch ref.Deprecated {
+	case "", "f", "false", "0", "n":
+		return false, ""
+	case "t", "true", "1", "y":
+		return true, ""
+	default:
+		return true, ref.Deprecated
+	}
+}
+
+// withoutEnum is used to describe map keys: enum only applies to values
+func (ref flagRef) withoutEnum() flagRef {
+	ref.Enum = ""
+	return ref
+}
+
+// flagTarget records a field that a flag fills so that the source of
+// its value can be checked after filling.
+type flagTarget struct {
+	model interface{}
+	path  string
+}
+
+type missingFlag struct {
+	ref   *flagRef
+	scope string // subcommand(s) that define the flag
 }
 
 type flagRefComparable struct {
@@ -193,14 +321,20 @@ var _ Filler = &FlagHandler{}
 //
 // Booleans are set with "--flag" or unset with "--no-flag".
 //
+// Use AllowAbbreviations to accept unique prefixes of long flags and subcommands.
+//
 // Flags are found using struct tags.  See the comment FlagHandler for details
 func PosixFlagHandler(opts ...FlaghandlerOptArg) *FlagHandler {
 	h := &FlagHandler{
 		fhInheritable: fhInheritable{
-			doubleDash:   true,
-			combineShort: true,
-			negativeNo:   true,
-			helpTag:      "help",
+			doubleDash:      true,
+			combineShort:    true,
+			negativeNo:      true,
+			helpTag:         "help",
+			suggestDistance: defaultSuggestDistance,
+			showDefaults:    true,
+			showEnv:         true,
+			groupSeparator:  defaultGroupSeparator,
 		},
 	}
 	h.init()
@@ -218,8 +352,12 @@ func PosixFlagHandler(opts ...FlaghandlerOptArg) *FlagHandler {
 func GoFlagHandler(opts ...FlaghandlerOptArg) *FlagHandler {
 	h := &FlagHandler{
 		fhInheritable: fhInheritable{
-			doubleDash: true,
-			singleDash: true,
+			doubleDash:      true,
+			singleDash:      true,
+			suggestDistance: defaultSuggestDistance,
+			showDefaults:    true,
+			showEnv:         true,
+			groupSeparator:  defaultGroupSeparator,
 		},
 	}
 	h.init()
@@ -230,9 +368,13 @@ func GoFlagHandler(opts ...FlaghandlerOptArg) *FlagHandler {
 func (h *FlagHandler) init() {
 	h.args = os.Args
 	h.subcommands = make(map[string]*FlagHandler)
+	h.subcommandAliases = make(map[string]string)
 	h.longFlags = make(map[string]*flagRef)
 	h.shortFlags = make(map[string]*flagRef)
 	h.mapFlags = make(map[string]*flagRef)
+	if h.stdin == nil {
+		h.stdin = &flagStdin{reader: os.Stdin}
+	}
 }
 
 func (h *FlagHandler) opts(opts []FlaghandlerOptArg) error {
@@ -265,7 +407,15 @@ func (h *FlagHandler) PreConfigure(tagName string, registry *Registry) error {
 			return err
 		}
 	}
-	err := h.parseFlags(1) // 0 is the program name so we skip it
+	err := h.expandResponseFiles()
+	if err != nil {
+		return err
+	}
+	err = h.parseFlags(1) // 0 is the program name so we skip it
+	if err != nil {
+		return err
+	}
+	err = h.checkRequired()
 	if err != nil {
 		return err
 	}
@@ -275,6 +425,12 @@ func (h *FlagHandler) PreConfigure(tagName string, registry *Registry) error {
 // ConfigureComplete is part of the Filler contract.  It is called by Registery.Configure
 func (h *FlagHandler) ConfigureComplete() error {
 	debug("flags: ConfigureComplete")
+	if h.Parent == nil && len(h.missingRequired) != 0 {
+		err := h.checkRequiredAfterFill()
+		if err != nil {
+			return err
+		}
+	}
 	if h.selectedSubcommand != "" {
 		err := h.subcommands[h.selectedSubcommand].ConfigureComplete()
 		if err != nil {
@@ -327,6 +483,16 @@ func Debugging(logger Logger) FlaghandlerOptArg {
 	}
 }
 
+// WarningLogger overrides where warnings are sent.  Warnings are generated
+// when deprecated flags are used.  The default is to print warnings to
+// os.Stderr.
+func WarningLogger(logger Logger) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.warnLogger = logger
+		return nil
+	}
+}
+
 // OnActivate is called before flags are parsed.  It's mostly for subcommands.  The
 // callback will be invoked as soon as it is known that the subcommand is being
 // used.
@@ -422,6 +588,11 @@ func WithDefaultsTag(defaultTag string) FlaghandlerOptArg {
 //
 // The default is "help".  To just change how the flag arguments are displayed
 // use "argName" in the "flag" tag.
+//
+// To list a flag in its own section of the usage message, use "section"
+// in the "flag" tag:
+//
+//	Port int `flag:"port,section=Networking" help:"Port to listen on"`
 func FlagHelpTag(helpTagName string) FlaghandlerOptArg {
 	return func(h *FlagHandler) error {
 		h.helpTag = helpTagName
@@ -474,6 +645,61 @@ func IgnoreSpecificFlagsFromEnv(envVar string) FlaghandlerOptArg {
 	}
 }
 
+// PermuteArgs allows flags and positional arguments to be interspersed, the
+// way GNU getopt does by default.  Flags are parsed wherever they appear and
+// positional arguments are collected, in order, into the remaining arguments:
+//
+//	prog file1 --verbose file2
+//
+// sets verbose and leaves "file1 file2" remaining.  "--" still ends flag
+// parsing and the arguments that follow it are added to the remaining
+// arguments as-is.
+//
+// When there are subcommands, the first positional argument selects the
+// subcommand (if it names one) and parsing continues with the subcommand's flags.
+func PermuteArgs() FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.permute = true
+		return nil
+	}
+}
+
+// AllowAbbreviations lets long flags and subcommands be given as
+// any unique prefix of their name, like GNU getopt_long.  With flags
+// "--verbose" and "--version", "--verb" means "--verbose" but "--ver" is
+// a UsageError because it is ambiguous.  Negations are abbreviated too:
+// "--no-verb" means "--no-verbose".
+//
+// Exact matches always win over abbreviations.  AllowAbbreviations is
+// intended for use with PosixFlagHandler.
+func AllowAbbreviations() FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.abbreviations = true
+		return nil
+	}
+}
+
+// RequiredSatisfiedBy allows values from other fillers to satisfy
+// flags marked "required".  The tags name the fillers that count, for
+// example:
+//
+//	PosixFlagHandler(RequiredSatisfiedBy("env", "config"))
+//
+// With this option, the check for missing required flags is deferred
+// until all fields have been filled and a required flag is only
+// reported if none of the named fillers provided a value for it.
+func RequiredSatisfiedBy(tags ...string) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		if h.satisfiedBy == nil {
+			h.satisfiedBy = make(map[string]bool)
+		}
+		for _, tag := range tags {
+			h.satisfiedBy[tag] = true
+		}
+		return nil
+	}
+}
+
 func (h *FlagHandler) addHelpFlagAndCommand(forceSub bool) error {
 	if h.helpAlreadyAdded || h.helpText == nil {
 		return nil
@@ -503,16 +729,15 @@ func (h *FlagHandler) addHelpFlagAndCommand(forceSub bool) error {
 			}
 		}
 	}
-	if forceSub || len(h.subcommands) > 0 {
+	if forceSub || len(h.subcommands) > 0 || len(h.helpTopics) > 0 {
 		_, err := h.AddSubcommand("help", "provide this usage info", nil, OnActivate(
-			func() {
-				if testMode {
-					testOutput = h.Usage()
-					panic("exit0")
-				} else {
-					fmt.Print(h.Usage())
-					os.Exit(0)
+			func(help *FlagHandler) error {
+				text, err := h.helpFor(help.args[help.firstArg:])
+				if err != nil {
+					return err
 				}
+				exitWithHelp(text)
+				return nil
 			}))
 		if err != nil {
 			return err
@@ -536,6 +761,9 @@ func (h *FlagHandler) AddSubcommand(command string, usageSummary string, configM
 			return nil, commonerrors.ProgrammerError(errors.Errorf("configModel must be a nil or a non-nil pointer to a struct, not %T", configModel))
 		}
 	}
+	if existing, ok := h.subcommandAliases[command]; ok {
+		return nil, commonerrors.ProgrammerError(errors.Errorf("subcommand %s is already an alias for %s", command, existing))
+	}
 	sub := &FlagHandler{
 		fhInheritable: h.fhInheritable,
 		Parent:        h,
@@ -559,8 +787,12 @@ func (h *FlagHandler) clearParse() {
 			ref.values = nil
 			ref.used = nil
 			ref.keys = nil
+			ref.filesRead = 0
 		}
 	}
+	if h.stdin != nil {
+		h.stdin.usedBy = ""
+	}
 	if h.selectedSubcommand != "" {
 		h.subcommands[h.selectedSubcommand].clearParse()
 		h.selectedSubcommand = ""
@@ -580,6 +812,11 @@ const (
 type opt struct {
 	name       string
 	help       string
+	group      string
+	dflt       string
+	env        string
+	config     string
+	notes      []string // annotations such as "[env: PORT]"
 	category   optCategory
 	f          reflect.StructField
 	nonPointer reflect.Type
@@ -606,7 +843,7 @@ func (o opt) format(doubleDash bool) string {
 		b.WriteString(o.name)
 		if o.ref.imported == nil && o.nonPointer.Kind() == reflect.Map {
 			b.WriteRune('<')
-			b.WriteString(o.describeArg(o.ref, o.nonPointer.Key(), "key", ""))
+			b.WriteString(o.describeArg(o.ref.withoutEnum(), o.nonPointer.Key(), "key", ""))
 			b.WriteString(">=<")
 			b.WriteString(o.describeArg(o.ref, o.nonPointer.Elem(), "value", ""))
 			b.WriteRune('>')
@@ -638,13 +875,13 @@ func (o opt) format(doubleDash bool) string {
 			case reflect.Map:
 				if o.ref.Map == "prefix" {
 					b.WriteRune('<')
-					b.WriteString(o.describeArg(o.ref, o.nonPointer.Key(), "key", ""))
+					b.WriteString(o.describeArg(o.ref.withoutEnum(), o.nonPointer.Key(), "key", ""))
 					b.WriteString(">=<")
 					b.WriteString(o.describeArg(o.ref, o.nonPointer.Elem(), "value", ""))
 					b.WriteRune('>')
 				} else {
 					b.WriteRune(' ')
-					b.WriteString(o.describeArg(o.ref, o.nonPointer.Key(), "key", ""))
+					b.WriteString(o.describeArg(o.ref.withoutEnum(), o.nonPointer.Key(), "key", ""))
 					b.WriteString(o.ref.Split)
 					b.WriteString(o.describeArg(o.ref, o.nonPointer.Elem(), "value", ""))
 				}
@@ -672,6 +909,9 @@ func (o opt) describeArg(ref flagRef, typ reflect.Type, name string, override st
 	if override != "" {
 		debugf("argname %s", override)
 	}
+	if ref.Content != "" && override == "" {
+		return ref.Content
+	}
 	switch typ.Kind() {
 	case reflect.Slice:
 		ed := o.describeArg(ref, typ.Elem(), name, override)
@@ -683,6 +923,9 @@ func (o opt) describeArg(ref flagRef, typ reflect.Type, name string, override st
 	if override != "" {
 		return override
 	}
+	if ref.Enum != "" {
+		return "{" + ref.Enum + "}"
+	}
 	switch typ.Kind() {
 	case reflect.Bool:
 		return "true|false"
@@ -718,158 +961,6 @@ func formatOpts(doubleDash bool, opts []*opt) []string {
 	return res
 }
 
-// Usage produces a usage summary.  It is not called automatically unless
-// WithHelpText is used in creation of the flag handler.
-func (h *FlagHandler) Usage() string {
-	// done := make(map[string]struct{})
-
-	// flags : -x
-	// options : -y foo
-	// parameters : --ything foo OR --ything=foo
-	// required: anything required
-
-	required := make([][]*opt, lastOpt)
-	optional := make([][]*opt, lastOpt)
-
-	for _, f := range h.rawData {
-		tagSet := reflectutils.SplitTag(f.Tag).Set()
-		ref, _, _, err := parseFlagRef(tagSet.Get(h.tagName), f.Type)
-		if err != nil {
-			panic(err.Error())
-		}
-		help := tagSet.Get(h.helpTag).Value
-		if help == "" {
-			help = fmt.Sprintf("set %s (%s)", f.Name, f.Type)
-		}
-		nonPointer := reflectutils.NonPointer(f.Type)
-		var lead *opt
-		for i, n := range ref.Name {
-			o := &opt{
-				name:       n,
-				help:       help,
-				f:          f,
-				primary:    i == 0,
-				ref:        ref,
-				nonPointer: nonPointer,
-			}
-			if i == 0 {
-				lead = o
-			} else {
-				lead.alts = append(lead.alts, o)
-			}
-
-			o.category = getCategory(n, ref)
-
-			if ref.Required {
-				required[o.category] = append(required[o.category], o)
-				break
-			}
-			optional[o.category] = append(optional[o.category], o)
-		}
-	}
-	// This is a non-overlapping set with h.rawData
-	for _, ref := range h.imported {
-		help := ref.imported.Usage
-		if ref.imported.DefValue != "" {
-			help += fmt.Sprintf(" (defaults to %s)", ref.imported.DefValue)
-		}
-		o := &opt{
-			name:    ref.imported.Name,
-			help:    help,
-			primary: true,
-			ref:     *ref,
-		}
-		o.category = getCategory(ref.Name[0], *ref)
-		optional[o.category] = append(optional[o.category], o)
-	}
-
-	usage := make([]string, 0, len(h.rawData)*2+10+len(h.subcommands)*2)
-	usage = append(usage, "Usage: "+h.args[0])
-
-	switch len(optional[flagOpt]) {
-	case 0:
-	default:
-		if h.combineShort {
-			usage = append(usage, " [-flags]")
-		} else {
-			usage = append(usage, " [flags]")
-		}
-	}
-	usage = append(usage, h.formatOpts(required[flagOpt])...)
-
-	switch len(optional[optionOpt]) {
-	case 0:
-	default:
-		if h.combineShort {
-			usage = append(usage, " [-options args]")
-		} else {
-			usage = append(usage, " [options]")
-		}
-	}
-	usage = append(usage, h.formatOpts(required[optionOpt])...)
-
-	switch len(optional[parameterOpt]) {
-	case 0:
-	default:
-		usage = append(usage, " [parameters]")
-	}
-	usage = append(usage, h.formatOpts(required[parameterOpt])...)
-
-	switch len(h.subcommandsOrder) {
-	case 0:
-	case 1:
-		if !h.helpAlreadyAdded || h.subcommandsOrder[0] != "help" {
-			usage = append(usage, " "+strings.Join(h.subcommandsOrder, "|")+" ")
-		}
-	case 2, 3, 4, 5, 6, 7:
-		usage = append(usage, " "+strings.Join(h.subcommandsOrder, "|")+" ")
-	default:
-		usage = append(usage, " subcommand")
-	}
-
-	if h.positionalHelp != "" {
-		usage = append(usage, " ", h.positionalHelp)
-	}
-	usage = append(usage, "\n")
-
-	if len(h.rawData) > 0 {
-		usage = append(usage, "\nOptions:\n")
-		for i := undefinedOpt; i < lastOpt; i++ {
-			for _, optSet := range [][]*opt{required[i], optional[i]} {
-				for _, opt := range optSet {
-					if !opt.primary {
-						continue
-					}
-					usage = append(usage, fmt.Sprintf(
-						"    %-30s %s\n",
-						opt.format(h.doubleDash),
-						strings.Join(notEmpty(
-							opt.formatAlts(h.doubleDash),
-							prependSpace(opt.help),
-						), " ")))
-				}
-			}
-		}
-	}
-
-	if len(h.subcommands) > 0 {
-		usage = append(usage, "\nSubcommands:\n")
-		for _, subcmd := range h.subcommandsOrder {
-			sub := h.subcommands[subcmd]
-			usage = append(usage, fmt.Sprintf(
-				"    %-20s %s\n",
-				subcmd,
-				sub.usageSummary))
-		}
-	}
-
-	if h.helpText != nil && *h.helpText != "" {
-		usage = append(usage, "\n", *h.helpText, "\n")
-	}
-
-	return strings.Join(usage, "")
-}
-
 func getCategory(name string, ref flagRef) optCategory {
 	switch utf8.RuneCountInString(name) {
 	case 1:
@@ -892,6 +983,15 @@ func (h *FlagHandler) debug(a ...any) {
 	}
 }
 
+func (h *FlagHandler) warnf(format string, a ...any) {
+	h.debugf("warning: "+format, a...)
+	if h.warnLogger != nil {
+		h.warnLogger.Logf(format, a...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+}
+
 func (h *FlagHandler) debugf(format string, a ...any) {
 	if debugging {
 		debugf("flags: "+format, a...)
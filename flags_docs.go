@@ -0,0 +1,416 @@
+package nfigure
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/muir/commonerrors"
+	"github.com/pkg/errors"
+)
+
+// DocOpt are options for GenerateManPages, GenerateMarkdown, and GenerateDocs
+type DocOpt func(*docConfig)
+
+type docConfig struct {
+	program string
+	tagName string
+	section string
+	date    string
+	manual  string
+	source  string
+	models  []interface{}
+}
+
+// DocProgram sets the name of the program.  The default is the
+// base name of os.Args[0] which is probably wrong when run
+// by "go generate".
+func DocProgram(name string) DocOpt {
+	return func(c *docConfig) {
+		c.program = name
+	}
+}
+
+// DocModels provides the models that would be passed to Registry.Request
+// for the top-level command.  This is not needed for subcommands since
+// their models are provided with AddSubcommand.  It is also not needed if
+// Registry.Configure has already been called.
+func DocModels(models ...interface{}) DocOpt {
+	return func(c *docConfig) {
+		c.models = append(c.models, models...)
+	}
+}
+
+// DocTag sets the tag used for flags.  The default is "flag".  It is not
+// needed if Registry.Configure has already been called.
+func DocTag(tagName string) DocOpt {
+	return func(c *docConfig) {
+		c.tagName = tagName
+	}
+}
+
+// DocManSection sets the man page section.  The default is "1".
+func DocManSection(section string) DocOpt {
+	return func(c *docConfig) {
+		c.section = section
+	}
+}
+
+// DocManHeader sets the date, source (eg: "myprog 1.2"), and manual
+// (eg: "User Commands") for the man page header.  They are left out by
+// default so that generated files only change when the flags do.
+func DocManHeader(date string, source string, manual string) DocOpt {
+	return func(c *docConfig) {
+		c.date = date
+		c.source = source
+		c.manual = manual
+	}
+}
+
+// docPage is the documentation for one command or subcommand
+type docPage struct {
+	name     string // "prog sub"
+	file     string // "prog-sub"
+	summary  string
+	aliases  []string
+	usage    UsageModel
+	parent   *docPage
+	children []*docPage
+}
+
+// GenerateDocs is meant to be the body of a small program that is
+// run by "go generate" to keep reference documentation up to date.  It
+// generates man pages and/or Markdown for h and its subcommands.
+// The arguments are:
+//
+//	-man directory       write roff man pages into directory
+//	-markdown directory  write Markdown into directory
+//
+// For example, with ExampleGenerateDocs as a fuller version of main:
+//
+//	//go:generate go run ./gendocs -man ../docs/man -markdown ../docs/reference
+//
+//	package main
+//
+//	func main() {
+//		fh := myapp.FlagHandler() // builds the PosixFlagHandler and its subcommands
+//		err := nfigure.GenerateDocs(fh, os.Args[1:],
+//			nfigure.DocProgram("myapp"),
+//			nfigure.DocModels(&myapp.Config{}))
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func GenerateDocs(h *FlagHandler, args []string, opts ...DocOpt) error {
+	fs := flag.NewFlagSet("GenerateDocs", flag.ContinueOnError)
+	man := fs.String("man", "", "directory for man pages")
+	markdown := fs.String("markdown", "", "directory for Markdown")
+	err := fs.Parse(args)
+	if err != nil {
+		return commonerrors.UsageError(err)
+	}
+	if *man == "" && *markdown == "" {
+		return commonerrors.UsageError(errors.New("at least one of -man and -markdown is required"))
+	}
+	if *man != "" {
+		err := h.GenerateManPages(*man, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	if *markdown != "" {
+		err := h.GenerateMarkdown(*markdown, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateManPages writes roff man pages for h and each of its subcommands
+// into dir.  The page for a subcommand is named for the command path, for
+// example "prog-sub.1".  Pages refer to each other in "SEE ALSO".
+// Hidden flags are left out.
+func (h *FlagHandler) GenerateManPages(dir string, opts ...DocOpt) error {
+	config := newDocConfig(h, opts)
+	root, err := h.docPages(config, nil, config.program)
+	if err != nil {
+		return err
+	}
+	return writeDocPages(dir, root, func(page *docPage) (string, string) {
+		return page.file + "." + config.section, manPage(config, page)
+	})
+}
+
+// GenerateMarkdown writes Markdown reference documentation for h and
+// each of its subcommands into dir.  The file for a subcommand is named
+// for the command path, for example "prog-sub.md".  Hidden flags are left out.
+func (h *FlagHandler) GenerateMarkdown(dir string, opts ...DocOpt) error {
+	config := newDocConfig(h, opts)
+	root, err := h.docPages(config, nil, config.program)
+	if err != nil {
+		return err
+	}
+	return writeDocPages(dir, root, func(page *docPage) (string, string) {
+		return page.file + ".md", markdownPage(page)
+	})
+}
+
+func newDocConfig(h *FlagHandler, opts []DocOpt) *docConfig {
+	config := &docConfig{
+		program: filepath.Base(h.args[0]),
+		tagName: h.tagName,
+		section: "1",
+	}
+	if config.tagName == "" {
+		config.tagName = "flag"
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// docPages builds the tree of pages.
+func (h *FlagHandler) docPages(config *docConfig, parent *docPage, name string) (*docPage, error) {
+	models := config.models
+	if h.Parent != nil {
+		models = nil
+		if h.configModel != nil {
+			models = []interface{}{h.configModel}
+		}
+	}
+	source, err := h.walkedForUsage(config.tagName, models)
+	if err != nil {
+		return nil, err
+	}
+	usage := source.UsageModel()
+	usage.Synopsis = name + strings.TrimPrefix(usage.Synopsis, usage.Program)
+	usage.Program = name
+	usage.Width = 0
+	page := &docPage{
+		name:    name,
+		file:    strings.ReplaceAll(name, " ", "-"),
+		summary: h.usageSummary,
+		aliases: h.aliases,
+		usage:   usage,
+		parent:  parent,
+	}
+	for _, subcmd := range h.subcommandsOrder {
+		if subcmd == "help" && h.helpAlreadyAdded {
+			continue
+		}
+		child, err := h.subcommands[subcmd].docPages(config, page, name+" "+subcmd)
+		if err != nil {
+			return nil, err
+		}
+		page.children = append(page.children, child)
+	}
+	return page, nil
+}
+
+// walkedForUsage returns a FlagHandler that can provide a UsageModel.
+// Subcommands that have not been selected have not been through PreWalk
+// so their models are walked using a copy of the FlagHandler.
+func (h *FlagHandler) walkedForUsage(tagName string, models []interface{}) (*FlagHandler, error) {
+	if len(h.rawData) == 0 && len(models) != 0 {
+		c := *h
+		c.tagName = tagName
+		c.rawData = nil
+		c.longFlags = make(map[string]*flagRef)
+		c.shortFlags = make(map[string]*flagRef)
+		c.mapFlags = make(map[string]*flagRef)
+		c.requiredRefs = nil
+		for _, model := range models {
+			err := c.PreWalk(tagName, model)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &c, nil
+	}
+	if h.tagName == "" {
+		c := *h
+		c.tagName = tagName
+		return &c, nil
+	}
+	return h, nil
+}
+
+func writeDocPages(dir string, page *docPage, render func(*docPage) (string, string)) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	file, content := render(page)
+	err = os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644) //nolint:gosec
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, child := range page.children {
+		err := writeDocPages(dir, child, render)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (page *docPage) isChild(name string) bool {
+	for _, child := range page.children {
+		if child.name == page.name+" "+name {
+			return true
+		}
+	}
+	return false
+}
+
+// seeAlso lists the parent and children of a page
+func (page *docPage) seeAlso() []*docPage {
+	var related []*docPage
+	if page.parent != nil {
+		related = append(related, page.parent)
+	}
+	return append(related, page.children...)
+}
+
+func manPage(config *docConfig, page *docPage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH %q %q %q %q %q\n",
+		strings.ToUpper(page.file), config.section, config.date, config.source, config.manual)
+	b.WriteString(".SH NAME\n")
+	b.WriteString(roffEscape(page.file))
+	if page.summary != "" {
+		b.WriteString(" \\- " + roffEscape(page.summary))
+	}
+	b.WriteString("\n.SH SYNOPSIS\n")
+	b.WriteString(".B " + roffEscape(page.name) + "\n")
+	if rest := strings.TrimSpace(strings.TrimPrefix(page.usage.Synopsis, page.name)); rest != "" {
+		b.WriteString(roffEscape(rest) + "\n")
+	}
+	if page.usage.HelpText != "" {
+		b.WriteString(".SH DESCRIPTION\n")
+		b.WriteString(roffText(page.usage.HelpText))
+	}
+	for i, section := range page.usage.Sections {
+		if i == 0 {
+			b.WriteString(".SH OPTIONS\n")
+		}
+		if section.Name != defaultUsageSection {
+			b.WriteString(".SS " + roffEscape(section.Name) + "\n")
+		}
+		for _, f := range section.Flags {
+			b.WriteString(".TP\n")
+			b.WriteString(".B " + roffEscape(strings.Join(notEmpty(f.Syntax, f.Alternates), " ")) + "\n")
+			b.WriteString(roffText(strings.Join(notEmpty(append([]string{f.Help}, f.Annotations...)...), " ")))
+		}
+	}
+	var commands []UsageSubcommand
+	for _, subcmd := range page.usage.Subcommands {
+		if page.isChild(subcmd.Name) {
+			commands = append(commands, subcmd)
+		}
+	}
+	if len(commands) != 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, subcmd := range commands {
+			b.WriteString(".TP\n")
+			b.WriteString(".B " + roffEscape(strings.Join(append([]string{subcmd.Name}, subcmd.Aliases...), ", ")) + "\n")
+			b.WriteString(roffText(subcmd.Summary))
+		}
+	}
+	if related := page.seeAlso(); len(related) != 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		for i, other := range related {
+			b.WriteString(".BR " + roffEscape(other.file) + " (" + config.section + ")")
+			if i < len(related)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// roffEscape makes s safe to use on a roff line
+func roffEscape(s string) string {
+	return strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+}
+
+// roffText escapes text and makes sure that no line looks like
+// a roff request
+func roffText(s string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		line = roffEscape(line)
+		switch {
+		case line == "":
+			b.WriteString(".PP\n")
+			continue
+		case strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'"):
+			b.WriteString(`\&`)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func markdownPage(page *docPage) string {
+	var b strings.Builder
+	b.WriteString("# " + page.name + "\n\n")
+	if page.summary != "" {
+		b.WriteString(page.summary + "\n\n")
+	}
+	b.WriteString("## Synopsis\n\n```\n" + page.usage.Synopsis + "\n```\n\n")
+	if page.usage.HelpText != "" {
+		b.WriteString(strings.TrimRight(page.usage.HelpText, "\n") + "\n\n")
+	}
+	for _, section := range page.usage.Sections {
+		b.WriteString("## " + section.Name + "\n\n")
+		for _, f := range section.Flags {
+			b.WriteString("- `" + f.Syntax + "`")
+			if f.Alternates != "" {
+				b.WriteString(" `" + f.Alternates + "`")
+			}
+			if help := strings.Join(notEmpty(append([]string{f.Help}, f.Annotations...)...), " "); help != "" {
+				b.WriteString(": " + markdownEscape(help))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	if len(page.children) != 0 {
+		b.WriteString("## Subcommands\n\n")
+		for _, child := range page.children {
+			b.WriteString("- [" + strings.TrimPrefix(child.name, page.name+" ") + "](" + child.file + ".md)")
+			if len(child.aliases) != 0 {
+				b.WriteString(" (" + markdownEscape(strings.Join(child.aliases, ", ")) + ")")
+			}
+			if child.summary != "" {
+				b.WriteString(": " + markdownEscape(child.summary))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	if page.parent != nil {
+		b.WriteString("## See also\n\n")
+		b.WriteString("- [" + page.parent.name + "](" + page.parent.file + ".md)\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"*", `\*`,
+		"_", `\_`,
+		"`", "\\`",
+		"[", `\[`,
+		"]", `\]`,
+		"<", `\<`,
+		">", `\>`,
+	).Replace(s)
+}
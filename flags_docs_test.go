@@ -0,0 +1,87 @@
+package nfigure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDocs(t *testing.T) {
+	type rootOptions struct {
+		Verbose bool   `flag:"verbose v" help:"print more"`
+		Secret  string `flag:"secret,hidden"`
+	}
+	type serveOptions struct {
+		Port int `flag:"port,section=Networking" default:"8080" help:"port to listen on"`
+	}
+	fh := PosixFlagHandler(WithHelpText(".dots\n\nmore-text"))
+	_, err := fh.AddSubcommand("serve", "run the server", &serveOptions{})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	err = GenerateDocs(fh, []string{"-man", filepath.Join(dir, "man"), "-markdown", filepath.Join(dir, "md")},
+		DocProgram("prog"),
+		DocModels(&rootOptions{}))
+	require.NoError(t, err)
+
+	read := func(file string) string {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		require.NoError(t, err, file)
+		return string(b)
+	}
+
+	assert.Equal(t, `.TH "PROG" "1" "" "" ""
+.SH NAME
+prog
+.SH SYNOPSIS
+.B prog
+[\-flags] [parameters] serve
+.SH DESCRIPTION
+\&.dots
+.PP
+more\-text
+.SH OPTIONS
+.TP
+.B [\-\-[no\-]verbose] [\-v]
+print more
+.SH COMMANDS
+.TP
+.B serve
+run the server
+.SH SEE ALSO
+.BR prog\-serve (1)
+`, read("man/prog.1"))
+
+	assert.Equal(t, `.TH "PROG-SERVE" "1" "" "" ""
+.SH NAME
+prog\-serve \- run the server
+.SH SYNOPSIS
+.B prog serve
+[parameters]
+.SH OPTIONS
+.SS Networking
+.TP
+.B [\-\-port=int]
+port to listen on [default: 8080]
+.SH SEE ALSO
+.BR prog (1)
+`, read("man/prog-serve.1"))
+
+	assert.Equal(t, "# prog serve\n\n"+
+		"run the server\n\n"+
+		"## Synopsis\n\n"+
+		"```\nprog serve [parameters]\n```\n\n"+
+		"## Networking\n\n"+
+		"- `[--port=int]`: port to listen on \\[default: 8080\\]\n\n"+
+		"## See also\n\n"+
+		"- [prog](prog.md)\n", read("md/prog-serve.md"))
+
+	root := read("md/prog.md")
+	assert.Contains(t, root, "- `[--[no-]verbose]` `[-v]`: print more\n")
+	assert.Contains(t, root, "## Subcommands\n\n- [serve](prog-serve.md): run the server\n")
+	assert.NotContains(t, root, "secret")
+	assert.NotContains(t, root, "help")
+}
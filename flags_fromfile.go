@@ -0,0 +1,68 @@
+package nfigure
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/muir/commonerrors"
+	"github.com/pkg/errors"
+)
+
+// flagStdin is shared between a FlagHandler and its subcommands
+// so that stdin is used by at most one flag per parse.  What is read
+// is kept so that parsing again gets the same value.
+type flagStdin struct {
+	reader   io.Reader
+	usedBy   string
+	read     bool
+	contents string
+}
+
+// WithStdin sets where flags with the "fromfile" option read "-" from.
+// The default is os.Stdin.
+func WithStdin(stdin io.Reader) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.stdin = &flagStdin{reader: stdin}
+		return nil
+	}
+}
+
+// readFromFiles is used for flags with the "fromfile" option. It replaces
+// values that are "@path" with the contents of the file at path and the
+// value "-" with the contents of stdin.  A single trailing newline is removed.
+// Values are only replaced once per parse.
+func (h *FlagHandler) readFromFiles(ref *flagRef) error {
+	for i := ref.filesRead; i < len(ref.values); i++ {
+		value := ref.values[i]
+		switch {
+		case value == "-":
+			if h.stdin.usedBy != "" {
+				return commonerrors.UsageError(errors.Errorf("%s: stdin was already read for %s", ref.used[i], h.stdin.usedBy))
+			}
+			h.stdin.usedBy = ref.used[i]
+			if !h.stdin.read {
+				b, err := io.ReadAll(h.stdin.reader)
+				if err != nil {
+					return commonerrors.UsageError(errors.Wrapf(err, "%s: read stdin", ref.used[i]))
+				}
+				h.stdin.read = true
+				h.stdin.contents = trimNewline(string(b))
+			}
+			ref.values[i] = h.stdin.contents
+		case strings.HasPrefix(value, "@"):
+			b, err := os.ReadFile(value[1:])
+			if err != nil {
+				return commonerrors.UsageError(errors.Wrap(err, ref.used[i]))
+			}
+			ref.values[i] = trimNewline(string(b))
+		}
+	}
+	ref.filesRead = len(ref.values)
+	return nil
+}
+
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
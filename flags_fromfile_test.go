@@ -0,0 +1,85 @@
+package nfigure
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/muir/commonerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagFromFile(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600))
+
+	type fromFileFlags struct {
+		Token   string            `flag:"token,fromfile"`
+		Body    string            `flag:"body,fromfile"`
+		Other   string            `flag:"other,fromfile"`
+		Literal string            `flag:"literal"`
+		Headers map[string]string `flag:"header,fromfile"`
+	}
+	cases := []struct {
+		name  string
+		args  []string
+		want  fromFileFlags
+		error string
+	}{
+		{
+			name: "file and stdin",
+			args: []string{"--token=@" + tokenFile, "--body", "-", "--other", "plain", "--literal", "@" + tokenFile, "--header", "X=@" + tokenFile},
+			want: fromFileFlags{
+				Token:   "s3cret",
+				Body:    "from\nstdin",
+				Other:   "plain",
+				Literal: "@" + tokenFile,
+				Headers: map[string]string{"X": "s3cret"},
+			},
+		},
+		{
+			name:  "stdin twice",
+			args:  []string{"--body=-", "--other=-"},
+			error: "--other: stdin was already read for --body",
+		},
+		{
+			name:  "missing file",
+			args:  []string{"--token=@" + filepath.Join(dir, "missing")},
+			error: "--token",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fh := PosixFlagHandler(WithArgs(tc.args), WithStdin(strings.NewReader("from\nstdin\n")))
+			registry := NewRegistry(WithFiller("flag", fh))
+			var got fromFileFlags
+			require.NoError(t, registry.Request(&got))
+			err := registry.Configure()
+			if tc.error != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.error)
+					assert.True(t, commonerrors.IsUsageError(err), "usage error")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFlagFromStdinParseTwice(t *testing.T) {
+	var got struct {
+		Body string `flag:"body,fromfile"`
+	}
+	fh := PosixFlagHandler(WithArgs([]string{"--body", "-"}), WithStdin(strings.NewReader("from stdin\n")))
+	registry := NewRegistry(WithFiller("flag", fh))
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	got.Body = ""
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, "from stdin", got.Body)
+}
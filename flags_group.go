@@ -0,0 +1,163 @@
+package nfigure
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/muir/nflex"
+	"github.com/muir/reflectutils"
+)
+
+const defaultGroupSeparator = "-"
+
+var (
+	_ CanRecurseFiller = &FlagHandler{}
+	_ CanRecurseFiller = &flagGroup{}
+	_ combiningFiller  = &flagGroup{}
+	_ contentFiller    = &flagGroup{}
+	_ sourceDescriber  = &flagGroup{}
+)
+
+// FlagGroupSeparator sets what goes between the name of a flag group
+// and the names of the flags within it.  The default is "-" so that
+//
+//	type DBConfig struct {
+//		Host string `flag:"host"`
+//	}
+//	type Config struct {
+//		DB DBConfig `flag:"db"`
+//	}
+//
+// fills DB.Host from --db-host.
+func FlagGroupSeparator(separator string) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.groupSeparator = separator
+		return nil
+	}
+}
+
+// flagField is a struct field that has a flag tag.  The prefix comes
+// from the flag groups that contain the field.
+type flagField struct {
+	reflect.StructField
+	prefix string
+}
+
+// flagGroup is used to fill the fields of a flag group.
+type flagGroup struct {
+	h      *FlagHandler
+	prefix string
+}
+
+// Fill is part of the Filler contract.  It is used for the fields
+// of flag groups.
+func (g *flagGroup) Fill(
+	t reflect.Type,
+	v reflect.Value,
+	tag reflectutils.Tag,
+	firstFirst bool,
+	combineObjects bool,
+) (bool, error) {
+	if combines(t, combineObjects) {
+		return false, nil
+	}
+	return g.h.fill(g.prefix, t, v, tag)
+}
+
+func (g *flagGroup) fillWhole(t reflect.Type, tag reflectutils.Tag) (reflect.Value, bool, error) {
+	return fillTemporary(t, func(v reflect.Value) (bool, error) {
+		return g.h.fill(g.prefix, t, v, tag)
+	})
+}
+
+func (g *flagGroup) describeSource(t reflect.Type, tag reflectutils.Tag, firstFirst bool) string {
+	return g.h.prefixedDescribeSource(g.prefix, t, tag)
+}
+
+func (g *flagGroup) content(t reflect.Type, tag reflectutils.Tag) (nflex.Source, bool, error) {
+	return g.h.prefixedContent(g.prefix, t, tag)
+}
+
+// Recurse is part of the CanRecurseFiller contract.  Recursion into
+// array elements and map values does not change flag names.
+func (g *flagGroup) Recurse(name string) (Filler, error) {
+	return g, nil
+}
+
+func (g *flagGroup) recurseField(name string, t reflect.Type, tag reflectutils.Tag) (Filler, error) {
+	if prefix, ok := g.h.flagGroupPrefix(t, tag); ok {
+		return &flagGroup{
+			h:      g.h,
+			prefix: g.prefix + prefix,
+		}, nil
+	}
+	return g, nil
+}
+
+// Recurse is part of the CanRecurseFiller contract.  Recursion only
+// changes anything when entering a flag group.
+func (h *FlagHandler) Recurse(name string) (Filler, error) {
+	return h, nil
+}
+
+func (h *FlagHandler) recurseField(name string, t reflect.Type, tag reflectutils.Tag) (Filler, error) {
+	if prefix, ok := h.flagGroupPrefix(t, tag); ok {
+		return &flagGroup{
+			h:      h,
+			prefix: prefix,
+		}, nil
+	}
+	return h, nil
+}
+
+// flagGroupPrefix returns true if a field of type t with the flag tag
+// is a flag group: a struct that has a flag tag but cannot itself be
+// set from a string.  The prefix is the group's name and the separator.
+func (h *FlagHandler) flagGroupPrefix(t reflect.Type, tag reflectutils.Tag) (string, bool) {
+	if tag.Tag == "" || t.Kind() != reflect.Struct {
+		return "", false
+	}
+	if _, err := reflectutils.MakeStringSetter(t); err == nil {
+		return "", false
+	}
+	var flagTag flagTag
+	if tag.Fill(&flagTag) == nil && flagTag.Content != "" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag.Value, ",")
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return "", true
+	}
+	return words[0] + h.groupSeparator, true
+}
+
+// groupPrefix combines the prefixes of the flag groups that contain the
+// field found at index in model type t.
+func (h *FlagHandler) groupPrefix(tagName string, t reflect.Type, index []int) string {
+	var prefix string
+	t = reflectutils.NonPointer(t)
+	for _, i := range index[:len(index)-1] {
+		f := t.Field(i)
+		if p, ok := h.flagGroupPrefix(f.Type, reflectutils.SplitTag(f.Tag).Set().Get(tagName)); ok {
+			prefix += p
+		}
+		t = f.Type
+	}
+	return prefix
+}
+
+// addPrefix renames a flag that is within a flag group.  Single-letter
+// names become long names.
+func (ref *flagRef) addPrefix(prefix string) {
+	if prefix == "" {
+		return
+	}
+	names := make([]string, 0, len(ref.Name))
+	for _, n := range ref.Name {
+		if n != "" {
+			names = append(names, prefix+n)
+		}
+	}
+	ref.Name = names
+}
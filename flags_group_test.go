@@ -0,0 +1,119 @@
+package nfigure
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nflex"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dbFlags struct {
+	Host string `flag:"host h" help:"database host"`
+	Port int    `flag:"port"`
+}
+
+type groupFlags struct {
+	Verbose bool    `flag:"v"`
+	Primary dbFlags `flag:"db"`
+	Replica struct {
+		DB      dbFlags `flag:"db"`
+		Enabled bool    `flag:"enabled"`
+	} `flag:"replica"`
+	Flat struct {
+		Name string `flag:"name"`
+	}
+}
+
+func TestFlagGroups(t *testing.T) {
+	cases := []struct {
+		cmd   string
+		opts  []FlaghandlerOptArg
+		want  groupFlags
+		error string
+	}{
+		{
+			cmd: "-v --db-host a --db-port 3 --replica-db-host b --replica-enabled --name n",
+			want: func() (g groupFlags) {
+				g.Verbose = true
+				g.Primary = dbFlags{Host: "a", Port: 3}
+				g.Replica.DB.Host = "b"
+				g.Replica.Enabled = true
+				g.Flat.Name = "n"
+				return g
+			}(),
+		},
+		{
+			cmd:   "--db-h a --replica.db.port=4",
+			opts:  []FlaghandlerOptArg{FlagGroupSeparator(".")},
+			error: "Flag --db-h not defined",
+		},
+		{
+			cmd:  "--db.h a --replica.db.port=4",
+			opts: []FlaghandlerOptArg{FlagGroupSeparator(".")},
+			want: func() (g groupFlags) {
+				g.Primary.Host = "a"
+				g.Replica.DB.Port = 4
+				return g
+			}(),
+		},
+		{
+			cmd:   "--host a",
+			error: "Flag --host not defined",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.cmd, func(t *testing.T) {
+			fh := PosixFlagHandler(append([]FlaghandlerOptArg{WithArgs(strings.Split(tc.cmd, " ")), UsageWidth(-1)}, tc.opts...)...)
+			registry := NewRegistry(WithFiller("flag", fh))
+			var got groupFlags
+			require.NoError(t, registry.Request(&got))
+			err := registry.Configure()
+			if tc.error != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.error)
+					assert.True(t, commonerrors.IsUsageError(err), "usage error")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFlagGroupUsage(t *testing.T) {
+	fh := PosixFlagHandler(WithArgs(nil), UsageWidth(-1))
+	registry := NewRegistry(WithFiller("flag", fh))
+	var got groupFlags
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	usage := fh.Usage()
+	assert.Contains(t, usage, "[--db-host=Host]               [--db-h=Host]  database host\n")
+	assert.Contains(t, usage, "[--replica-db-port=int]")
+	assert.Contains(t, usage, "[--name=Name]")
+}
+
+func TestFlagGroupFromRoot(t *testing.T) {
+	type libraryConfig struct {
+		DB    dbFlags `flag:"lib-db" config:"db"`
+		Other int     `config:"II"`
+	}
+	fh := PosixFlagHandler(WithArgs([]string{"--lib-db-port", "7"}))
+	registry := NewRegistry(
+		WithFiller("flag", fh),
+		WithFiller("config", NewFileFiller(WithUnmarshalOpts(nflex.WithFS(content)))))
+	require.NoError(t, registry.ConfigFile("source.yaml"))
+	var got struct {
+		Library libraryConfig
+	}
+	var mounted libraryConfig
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Request(&mounted, FromRoot("MM")))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, 7, got.Library.DB.Port)
+	assert.Equal(t, 7, mounted.DB.Port)
+}
@@ -0,0 +1,126 @@
+package nfigure
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/muir/commonerrors"
+	"github.com/pkg/errors"
+)
+
+type helpTopic struct {
+	name    string
+	summary string
+	text    string
+}
+
+// SubcommandAliases provides alternative names for a subcommand.  It is an
+// option for AddSubcommand:
+//
+//	fh.AddSubcommand("remove", "remove files", &removeOptions{}, SubcommandAliases("rm"))
+//
+// Aliases are listed with the subcommand in the usage message and are
+// considered for abbreviations and suggestions.
+func SubcommandAliases(aliases ...string) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		if h.Parent == nil {
+			return commonerrors.ProgrammerError(errors.New("SubcommandAliases is only for subcommands"))
+		}
+		for _, alias := range aliases {
+			if _, ok := h.Parent.subcommands[alias]; ok {
+				return commonerrors.ProgrammerError(errors.Errorf("alias %s is already a subcommand", alias))
+			}
+			if existing, ok := h.Parent.subcommandAliases[alias]; ok {
+				return commonerrors.ProgrammerError(errors.Errorf("alias %s is already an alias for %s", alias, existing))
+			}
+			h.Parent.subcommandAliases[alias] = h.Parent.subcommandsOrder[len(h.Parent.subcommandsOrder)-1]
+			h.aliases = append(h.aliases, alias)
+		}
+		return nil
+	}
+}
+
+// HelpTopic adds a topic, that is not a subcommand, to what the "help"
+// subcommand can explain.  For example, "help environment" could describe
+// the environment variables that a program uses.  Topics are listed in
+// the usage message with their summary.  The text is printed as-is.
+// The "help" subcommand is only available when WithHelpText is used.
+func HelpTopic(name string, summary string, text string) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.helpTopics = append(h.helpTopics, helpTopic{
+			name:    name,
+			summary: summary,
+			text:    text,
+		})
+		return nil
+	}
+}
+
+// helpFor finds what "help" should print: with no words, it's the usage
+// message for h.  Words select subcommands and help topics.
+func (h *FlagHandler) helpFor(words []string) (string, error) {
+	target := h
+	var names []string
+	for _, word := range words {
+		if word == "--" || strings.HasPrefix(word, "-") {
+			break
+		}
+		name, err := target.resolveSubcommand(word)
+		if err != nil {
+			return "", err
+		}
+		if sub, ok := target.subcommands[name]; ok && name != "help" {
+			target = sub
+			names = append(names, name)
+			continue
+		}
+		for _, topic := range target.helpTopics {
+			if topic.name == word {
+				if strings.HasSuffix(topic.text, "\n") {
+					return topic.text, nil
+				}
+				return topic.text + "\n", nil
+			}
+		}
+		return "", commonerrors.UsageError(errors.Errorf("No help for %s", word))
+	}
+	if target == h {
+		return h.Usage(), nil
+	}
+	var models []interface{}
+	if target.configModel != nil {
+		models = []interface{}{target.configModel}
+	}
+	source, err := target.walkedForUsage(h.tagName, models)
+	if err != nil {
+		return "", err
+	}
+	model := source.UsageModel()
+	program := strings.Join(append([]string{h.args[0]}, names...), " ")
+	model.Synopsis = program + strings.TrimPrefix(model.Synopsis, model.Program)
+	model.Program = program
+	return source.renderUsage(model), nil
+}
+
+// resolveSubcommand turns aliases and, with AllowAbbreviations,
+// abbreviations into the name of a subcommand.
+func (h *FlagHandler) resolveSubcommand(word string) (string, error) {
+	if name, ok := h.subcommandAliases[word]; ok {
+		return name, nil
+	}
+	if h.abbreviations && len(h.subcommands) > 0 {
+		return h.expandSubcommand(word)
+	}
+	return word, nil
+}
+
+// exitWithHelp prints text and exits
+func exitWithHelp(text string) {
+	if testMode {
+		testOutput = text
+		panic("exit0")
+	}
+	fmt.Print(text)
+	os.Exit(0)
+}
@@ -0,0 +1,125 @@
+package nfigure
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/muir/commonerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubcommandAliasesAndHelpTopics(t *testing.T) {
+	type removeOptions struct {
+		Force bool `flag:"force f" help:"remove without asking"`
+	}
+	type listOptions struct {
+		Long bool `flag:"l"`
+	}
+	cases := []struct {
+		cmd      string
+		opts     []FlaghandlerOptArg
+		selected string
+		force    bool
+		output   string
+		contains []string
+		error    string
+	}{
+		{
+			cmd:      "rm -f",
+			selected: "remove",
+			force:    true,
+		},
+		{
+			cmd:      "l",
+			opts:     []FlaghandlerOptArg{AllowAbbreviations()},
+			selected: "list",
+		},
+		{
+			cmd:   "rn",
+			opts:  []FlaghandlerOptArg{DidYouMean(1)},
+			error: "Unknown subcommand rn, did you mean rm?",
+		},
+		{
+			cmd: "help",
+			contains: []string{
+				"    remove, rm           remove files\n",
+				"    list, ls             list files\n",
+				"\nHelp topics:\n    environment          environment variables\n",
+			},
+		},
+		{
+			cmd:      "help rm",
+			contains: []string{"Usage: prog remove [-flags] [parameters]\n", "remove without asking"},
+		},
+		{
+			cmd:    "help environment",
+			output: "HOME is used\n",
+		},
+		{
+			cmd:   "help bogus",
+			error: "No help for bogus",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.cmd, func(t *testing.T) {
+			savedArgs := os.Args
+			t.Cleanup(func() { os.Args = savedArgs })
+			os.Args = []string{"prog"}
+			fh := PosixFlagHandler(append([]FlaghandlerOptArg{
+				WithArgs(strings.Split(tc.cmd, " ")),
+				WithHelpText(""),
+				UsageWidth(-1),
+				HelpTopic("environment", "environment variables", "HOME is used"),
+			}, tc.opts...)...)
+			var remove removeOptions
+			var selected string
+			_, err := fh.AddSubcommand("remove", "remove files", &remove,
+				SubcommandAliases("rm"), OnStart(func() { selected = "remove" }))
+			require.NoError(t, err)
+			_, err = fh.AddSubcommand("list", "list files", &listOptions{},
+				SubcommandAliases("ls"), OnStart(func() { selected = "list" }))
+			require.NoError(t, err)
+			registry := NewRegistry(WithFiller("flag", fh))
+
+			if tc.output != "" || tc.contains != nil {
+				testMode = true
+				testOutput = ""
+				defer func() { testMode = false }()
+				assert.PanicsWithValue(t, "exit0", func() {
+					_ = registry.Configure()
+					panic("not this value")
+				})
+				if tc.output != "" {
+					assert.Equal(t, tc.output, testOutput)
+				}
+				for _, want := range tc.contains {
+					assert.Contains(t, testOutput, want)
+				}
+				return
+			}
+			err = registry.Configure()
+			if tc.error != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.error)
+					assert.True(t, commonerrors.IsUsageError(err), "usage error")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.selected, selected)
+			assert.Equal(t, tc.force, remove.Force)
+		})
+	}
+}
+
+func TestSubcommandAliasConflict(t *testing.T) {
+	fh := PosixFlagHandler()
+	_, err := fh.AddSubcommand("remove", "", nil, SubcommandAliases("rm"))
+	require.NoError(t, err)
+	_, err = fh.AddSubcommand("rm", "", nil)
+	assert.True(t, commonerrors.IsProgrammerError(err), "programmer error")
+	_, err = fh.AddSubcommand("delete", "", nil, SubcommandAliases("rm"))
+	assert.True(t, commonerrors.IsProgrammerError(err), "programmer error")
+}
@@ -156,6 +156,7 @@ func ExportToFlagSet(fs FlagSet, tagName string, model interface{}, opts ...Flag
 		if err != nil {
 			return err
 		}
+		ref.addPrefix(f.prefix)
 		setter, err := reflectutils.MakeStringSetter(setterType, reflectutils.WithSplitOn(ref.Split))
 		if err != nil {
 			return commonerrors.UsageError(errors.Wrap(err, f.Name))
@@ -2,14 +2,15 @@ package nfigure
 
 import (
 	"fmt"
-	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/muir/commonerrors"
+	"github.com/muir/nflex"
 	"github.com/muir/reflectutils"
 	"github.com/pkg/errors"
 )
@@ -42,7 +43,100 @@ func (h *FlagHandler) skipNextArgumentIfNotFlag(currentIndex int, flagName strin
 	return currentIndex
 }
 
-func (h *FlagHandler) parseFlags(i int) error {
+// expandAbbreviation turns a unique prefix of a long flag name, possibly
+// with a "no-" prefix and "=value" suffix, into the full flag name.
+// Unknown flags are returned unchanged.
+func (h *FlagHandler) expandAbbreviation(dash string, noDash string) (string, error) {
+	name, value := noDash, ""
+	if i := strings.IndexByte(noDash, '='); i != -1 {
+		name, value = noDash[:i], noDash[i:]
+		if h.mapRE != nil && h.mapRE.MatchString(name) {
+			return noDash, nil
+		}
+	}
+	if _, ok := h.longFlags[name]; ok || h.shouldIgnoreFlag(name) {
+		return noDash, nil
+	}
+	var negate string
+	if h.negativeNo && strings.HasPrefix(name, "no-") {
+		if ref, ok := h.longFlags[name[3:]]; ok && ref.isBool {
+			return noDash, nil
+		}
+		negate = "no-"
+	}
+	var candidates []string
+	seen := make(map[*flagRef]struct{})
+	for _, prefix := range notEmpty(name, strings.TrimPrefix(name, negate)) {
+		isNegation := prefix != name
+		for full, ref := range h.longFlags {
+			if !strings.HasPrefix(full, prefix) {
+				continue
+			}
+			if isNegation && !ref.isBool {
+				continue
+			}
+			if _, ok := seen[ref]; ok {
+				continue
+			}
+			seen[ref] = struct{}{}
+			if isNegation {
+				full = negate + full
+			}
+			candidates = append(candidates, full)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return noDash, nil
+	case 1:
+		h.debugf("abbreviation %s%s expanded to %s%s", dash, name, dash, candidates[0])
+		return candidates[0] + value, nil
+	default:
+		sort.Strings(candidates)
+		return "", commonerrors.UsageError(errors.Errorf("Flag %s%s is ambiguous, it could be %s%s",
+			dash, name, dash, strings.Join(candidates, " or "+dash)))
+	}
+}
+
+// expandSubcommand turns a unique prefix of a subcommand name into the
+// full name.  Unknown subcommands are returned unchanged.
+func (h *FlagHandler) expandSubcommand(word string) (string, error) {
+	if _, ok := h.subcommands[word]; ok || word == "" {
+		return word, nil
+	}
+	var candidates []string
+	for _, name := range h.subcommandsOrder {
+		for _, n := range append([]string{name}, h.subcommands[name].aliases...) {
+			if strings.HasPrefix(n, word) {
+				candidates = append(candidates, name)
+				break
+			}
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return word, nil
+	case 1:
+		h.debugf("abbreviation %s expanded to subcommand %s", word, candidates[0])
+		return candidates[0], nil
+	default:
+		return "", commonerrors.UsageError(errors.Errorf("Subcommand %s is ambiguous, it could be %s",
+			word, strings.Join(candidates, " or ")))
+	}
+}
+
+func (h *FlagHandler) parseFlags(start int) error {
+	i, err := h.parseArgs(start)
+	if err != nil && h.selectedSubcommand == "" {
+		return h.blameResponseFile(i, err)
+	}
+	return err
+}
+
+// parseArgs parses h.args starting at i.  It returns the index of the
+// argument being parsed when it stopped so that errors can be attributed
+// to response files.
+func (h *FlagHandler) parseArgs(i int) (int, error) {
 	h.debug("beginning parse")
 	if h.alreadyParsed {
 		h.clearParse()
@@ -50,9 +144,11 @@ func (h *FlagHandler) parseFlags(i int) error {
 	h.alreadyParsed = true
 	err := h.addHelpFlagAndCommand(false)
 	if err != nil {
-		return err
+		return i, err
 	}
 	var remainder []string
+	var from string          // set when the current argument is from a response file
+	var positionals []string // only used with PermuteArgs
 
 	if len(h.mapFlags) > 0 {
 		me := make([]string, 0, len(h.mapFlags))
@@ -62,19 +158,20 @@ func (h *FlagHandler) parseFlags(i int) error {
 		var err error
 		h.mapRE, err = regexp.Compile(`^(` + strings.Join(me, "|") + `)(.+)$`)
 		if err != nil {
-			return commonerrors.LibraryError(errors.Wrap(err, "unexpected internal error"))
+			return i, commonerrors.LibraryError(errors.Wrap(err, "unexpected internal error"))
 		}
 		h.debugf("parseflags mapRE = %s", h.mapRE)
 	}
 
 	handleFollowingArgs := func(ref *flagRef, flag string, withDash string, inErr string) error {
+		start := len(ref.values)
 		switch {
 		case ref.isBool:
 			ref.values = append(ref.values, "t")
-			ref.used = append(ref.used, withDash)
+			ref.used = append(ref.used, withDash+from)
 		case ref.IsCounter:
 			ref.values = append(ref.values, "")
-			ref.used = append(ref.used, withDash)
+			ref.used = append(ref.used, withDash+from)
 		case ref.isMap:
 			if i+1 >= len(h.args) {
 				return commonerrors.UsageError(errors.Errorf("Expecting a positional argument after %s, none is available", inErr))
@@ -87,7 +184,7 @@ func (h *FlagHandler) parseFlags(i int) error {
 			h.debugf("parse map split %s = %s", kv[0], kv[1])
 			ref.keys = append(ref.keys, kv[0])
 			ref.values = append(ref.values, kv[1])
-			ref.used = append(ref.used, withDash)
+			ref.used = append(ref.used, withDash+from)
 		default:
 			count := 1
 			if ref.explode != 0 {
@@ -99,10 +196,10 @@ func (h *FlagHandler) parseFlags(i int) error {
 			}
 			i++
 			ref.values = append(ref.values, h.args[i:i+count]...)
-			ref.used = append(ref.used, repeatString(withDash, count)...)
+			ref.used = append(ref.used, repeatString(withDash+from, count)...)
 			i += count - 1
 		}
-		return nil
+		return h.noteUse(ref, start)
 	}
 
 	handleShort := func(flag string, inErr string) error {
@@ -117,6 +214,13 @@ func (h *FlagHandler) parseFlags(i int) error {
 	}
 
 	longFlag := func(dash string, noDash string) (bool, error) {
+		if h.abbreviations {
+			var err error
+			noDash, err = h.expandAbbreviation(dash, noDash)
+			if err != nil {
+				return false, err
+			}
+		}
 		if i := strings.IndexByte(noDash, '='); i != -1 {
 			flag := noDash[0:i]
 			value := noDash[i+1:]
@@ -128,16 +232,16 @@ func (h *FlagHandler) parseFlags(i int) error {
 						dash, flag, ref.explode, dash, flag))
 				}
 				ref.values = append(ref.values, value)
-				ref.used = append(ref.used, dash+flag)
-				return true, nil
+				ref.used = append(ref.used, dash+flag+from)
+				return true, h.noteUse(ref, len(ref.values)-1)
 			}
 			if h.mapRE != nil {
 				if m := h.mapRE.FindStringSubmatch(flag); len(m) > 0 {
 					if ref, ok := h.mapFlags[m[1]]; ok {
 						ref.keys = append(ref.keys, m[2])
 						ref.values = append(ref.values, value)
-						ref.used = append(ref.used, dash+m[1])
-						return true, nil
+						ref.used = append(ref.used, dash+m[1]+from)
+						return true, h.noteUse(ref, len(ref.values)-1)
 					}
 					return false, commonerrors.LibraryError(errors.New("internal error: expected to find mapFlag"))
 				}
@@ -145,7 +249,7 @@ func (h *FlagHandler) parseFlags(i int) error {
 			if h.shouldIgnoreFlag(flag) {
 				return true, nil // silently ignore this flag
 			}
-			return false, commonerrors.UsageError(errors.Errorf("Flag %s%s not defined", dash, flag))
+			return false, h.unknownFlagError(dash, flag)
 		}
 		if ref, ok := h.longFlags[noDash]; ok {
 			return true, handleFollowingArgs(ref, noDash, dash+noDash, dash+noDash)
@@ -153,23 +257,28 @@ func (h *FlagHandler) parseFlags(i int) error {
 		if h.negativeNo && strings.HasPrefix(noDash, "no-") {
 			if ref, ok := h.longFlags[noDash[3:]]; ok && ref.isBool {
 				ref.values = append(ref.values, "f")
-				ref.used = append(ref.used, dash+noDash)
-				return true, nil
+				ref.used = append(ref.used, dash+noDash+from)
+				return true, h.noteUse(ref, len(ref.values)-1)
 			}
 		}
 		if h.shouldIgnoreFlag(noDash) {
 			return true, nil // silently ignore this flag
 		}
-		return false, commonerrors.UsageError(errors.Errorf("Flag %s%s not defined", dash, noDash))
+		return false, h.unknownFlagError(dash, noDash)
 	}
 
 	for ; i < len(h.args); i++ {
 		f := h.args[i]
+		from = h.fromFile(i)
 		if f == "--" {
-			remainder = h.args[i+1:]
+			if h.permute {
+				remainder = append(positionals, h.args[i+1:]...)
+			} else {
+				remainder = h.args[i+1:]
+			}
 			h.debugf("found -- remaining flags (%d/%d) are positional", i, len(h.args))
 			if len(remainder) > 0 && h.noPositional {
-				return commonerrors.UsageError(errors.Errorf("flags parsing completed, with --, leaving %d unexpected positional arguments: %v",
+				return i, commonerrors.UsageError(errors.Errorf("flags parsing completed, with --, leaving %d unexpected positional arguments: %v",
 					len(remainder), remainder))
 			}
 			break
@@ -179,7 +288,7 @@ func (h *FlagHandler) parseFlags(i int) error {
 			handled, err := longFlag("--", flagName)
 			if err != nil {
 				h.debugf("at %d, failed long flag %s", i, f)
-				return err
+				return i, err
 			}
 			if handled {
 				h.debugf("at %d, long flag %s handled", i, f)
@@ -195,7 +304,7 @@ func (h *FlagHandler) parseFlags(i int) error {
 			if h.singleDash && utf8.RuneCountInString(f[1:]) > 1 {
 				handled, err := longFlag("-", f[1:])
 				if err != nil {
-					return err
+					return i, err
 				}
 				if handled {
 					continue
@@ -208,7 +317,7 @@ func (h *FlagHandler) parseFlags(i int) error {
 					err := handleShort(string(r), fmt.Sprintf(
 						"-%c (in %s)", r, f))
 					if err != nil {
-						return err
+						return i, err
 					}
 					potentialFlags = potentialFlags[size:]
 				}
@@ -217,7 +326,7 @@ func (h *FlagHandler) parseFlags(i int) error {
 			flagName := f[1:]
 			err := handleShort(flagName, f)
 			if err != nil {
-				return err
+				return i, err
 			}
 			// If the flag was ignored, skip the next argument if it's not a flag
 			if h.shouldIgnoreFlag(flagName) {
@@ -225,48 +334,219 @@ func (h *FlagHandler) parseFlags(i int) error {
 			}
 			continue
 		}
-		if sub, ok := h.subcommands[f]; ok {
-			h.debugf("at %d, selecting subcommand %s", i, f)
-			if sub.configModel != nil {
-				err := h.registry.Request(sub.configModel,
-					WithFiller(h.tagName, sub))
+		// With PermuteArgs, only the first positional argument can be a subcommand
+		if len(positionals) == 0 {
+			if len(h.subcommands) > 0 {
+				var err error
+				f, err = h.resolveSubcommand(f)
 				if err != nil {
-					return err
+					return i, err
 				}
 			}
-			h.selectedSubcommand = f
-			sub.tagName = h.tagName   // set late (by PreConfigure) so must be propagated
-			sub.registry = h.registry // set late (by PreConfigure) so must be propagated
-			if sub.onActivate != nil {
-				err := sub.onActivate(h.registry, sub)
-				if err != nil {
-					return err
+			if sub, ok := h.subcommands[f]; ok {
+				h.debugf("at %d, selecting subcommand %s", i, f)
+				if sub.configModel != nil {
+					err := h.registry.Request(sub.configModel,
+						WithFiller(h.tagName, sub))
+					if err != nil {
+						return i, err
+					}
+				}
+				h.selectedSubcommand = f
+				sub.tagName = h.tagName   // set late (by PreConfigure) so must be propagated
+				sub.registry = h.registry // set late (by PreConfigure) so must be propagated
+				sub.args = h.args
+				sub.argOrigins = h.argOrigins
+				sub.firstArg = i + 1
+				if sub.onActivate != nil {
+					err := sub.onActivate(h.registry, sub)
+					if err != nil {
+						return i, err
+					}
 				}
+				return i, sub.parseFlags(sub.firstArg)
+			}
+			if err := h.unknownSubcommandError(f); err != nil {
+				return i, err
 			}
-			sub.args = h.args
-			return sub.parseFlags(i + 1)
+		}
+		if h.permute {
+			h.debugf("at %d, positional %s", i, f)
+			positionals = append(positionals, f)
+			continue
 		}
 		remainder = h.args[i:]
 		h.debugf("at %d, remainder is %v", i, remainder)
 		if len(remainder) > 0 && h.noPositional {
-			return commonerrors.UsageError(errors.Errorf("flags parsing completed leaving %d unexpected positional arguments: %v",
+			return i, commonerrors.UsageError(errors.Errorf("flags parsing completed leaving %d unexpected positional arguments: %v",
 				len(remainder), remainder))
 		}
 		break
 	}
-	if h.helpText != nil && len(h.longFlags["help"].values) != 0 {
-		if testMode {
-			testOutput = h.Usage()
-			panic("exit0")
-		} else {
-			fmt.Print(h.Usage())
-			os.Exit(0)
+	if h.permute && remainder == nil {
+		remainder = positionals
+		if len(remainder) > 0 && h.noPositional {
+			return i, commonerrors.UsageError(errors.Errorf("flags parsing completed leaving %d unexpected positional arguments: %v",
+				len(remainder), remainder))
 		}
 	}
+	if h.helpText != nil && len(h.longFlags["help"].values) != 0 {
+		exitWithHelp(h.Usage())
+	}
 	h.remainder = remainder
+	return i, nil
+}
+
+// noteUse is called after values have been added to ref, starting at
+// index start.  Deprecated flags generate a warning and may forward their values
+// to their replacement.
+func (h *FlagHandler) noteUse(ref *flagRef, start int) error {
+	deprecated, message := ref.deprecation()
+	if !deprecated || start >= len(ref.values) {
+		return nil
+	}
+	h.warnf("Flag %s is deprecated%s", ref.used[start], prependColon(message))
+	if ref.ReplacedBy == "" {
+		return nil
+	}
+	target, ok := h.longFlags[ref.ReplacedBy]
+	if !ok {
+		target, ok = h.shortFlags[ref.ReplacedBy]
+	}
+	if !ok {
+		target, ok = h.mapFlags[ref.ReplacedBy]
+	}
+	if !ok {
+		return commonerrors.ProgrammerError(errors.Errorf("replacedBy=%s for flag %s: no such flag", ref.ReplacedBy, ref.Name[0]))
+	}
+	if target.isMap != ref.isMap {
+		return commonerrors.ProgrammerError(errors.Errorf("replacedBy=%s for flag %s: cannot forward between maps and non-maps", ref.ReplacedBy, ref.Name[0]))
+	}
+	h.debugf("forwarding deprecated flag %s to %s", ref.Name[0], ref.ReplacedBy)
+	target.values = append(target.values, ref.values[start:]...)
+	target.used = append(target.used, ref.used[start:]...)
+	if ref.isMap {
+		target.keys = append(target.keys, ref.keys[start:]...)
+	}
 	return nil
 }
 
+// checkRequired looks for required flags that were not given in this
+// handler and in the selected subcommand(s).  If RequiredSatisfiedBy is in
+// use, the missing flags are remembered and checked again after filling.
+func (h *FlagHandler) checkRequired() error {
+	h.missingRequired = h.findMissingRequired("")
+	if len(h.missingRequired) == 0 {
+		return nil
+	}
+	if len(h.satisfiedBy) != 0 {
+		h.debugf("deferring check of %d missing required flags", len(h.missingRequired))
+		return nil
+	}
+	return h.missingRequiredError(h.missingRequired)
+}
+
+func (h *FlagHandler) findMissingRequired(scope string) []missingFlag {
+	var missing []missingFlag
+	for _, ref := range h.requiredRefs {
+		if len(ref.values) == 0 {
+			missing = append(missing, missingFlag{
+				ref:   ref,
+				scope: scope,
+			})
+		}
+	}
+	if h.selectedSubcommand != "" {
+		missing = append(missing, h.subcommands[h.selectedSubcommand].findMissingRequired(
+			strings.TrimPrefix(scope+" "+h.selectedSubcommand, " "))...)
+	}
+	return missing
+}
+
+// checkRequiredAfterFill is used with RequiredSatisfiedBy.  Missing flags are
+// only an error if none of the fillers allowed by RequiredSatisfiedBy provided
+// a value for the field.
+func (h *FlagHandler) checkRequiredAfterFill() error {
+	requests := h.registry.GetRequests()
+	var stillMissing []missingFlag
+	for _, missing := range h.missingRequired {
+		if !h.satisfiedElsewhere(requests, missing.ref) {
+			stillMissing = append(stillMissing, missing)
+		}
+	}
+	if len(stillMissing) == 0 {
+		return nil
+	}
+	return h.missingRequiredError(stillMissing)
+}
+
+func (h *FlagHandler) satisfiedElsewhere(requests []*Request, ref *flagRef) bool {
+	for _, target := range ref.targets {
+		for _, request := range requests {
+			if request.object != target.model {
+				continue
+			}
+			if tag, ok := request.FilledBy(target.path); ok && h.satisfiedBy[tag] {
+				h.debugf("required flag %s satisfied by %s", ref.Name[0], tag)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (h *FlagHandler) missingRequiredError(missing []missingFlag) error {
+	names := make([]string, len(missing))
+	for i, m := range missing {
+		names[i] = h.describeFlag(m.ref)
+		if m.scope != "" {
+			names[i] += " (" + m.scope + ")"
+		}
+	}
+	return commonerrors.UsageError(errors.Errorf("Missing required flag(s): %s", strings.Join(names, ", ")))
+}
+
+// describeFlag returns all the ways a flag can be given, eg: "-u/--user"
+func (h *FlagHandler) describeFlag(ref *flagRef) string {
+	names := make([]string, 0, len(ref.Name))
+	for _, n := range ref.Name {
+		switch utf8.RuneCountInString(n) {
+		case 0:
+		case 1:
+			names = append(names, "-"+n)
+		default:
+			if h.doubleDash {
+				names = append(names, "--"+n)
+			} else {
+				names = append(names, "-"+n)
+			}
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+func (h *FlagHandler) describeSource(t reflect.Type, tag reflectutils.Tag, firstFirst bool) string {
+	return h.prefixedDescribeSource("", t, tag)
+}
+
+// prefixedDescribeSource names the flag that filled a value: the name that
+// was used on the command line if there is one.
+func (h *FlagHandler) prefixedDescribeSource(prefix string, t reflect.Type, tag reflectutils.Tag) string {
+	rawRef, _, _, err := parseFlagRef(tag, t)
+	if err != nil {
+		return ""
+	}
+	rawRef.addPrefix(prefix)
+	for _, n := range rawRef.Name {
+		for _, m := range []map[string]*flagRef{h.longFlags, h.shortFlags, h.mapFlags} {
+			if ref, ok := m[n]; ok && len(ref.used) != 0 {
+				return ref.used[len(ref.used)-1]
+			}
+		}
+	}
+	return h.describeFlag(&rawRef)
+}
+
 // Remaining returns the arguments that were not consumed from arguments (os.Args or WithArgs()). The other way
 // to get the remaining arguments is to add an OnStart callback.
 func (h *FlagHandler) Remaining() []string {
@@ -286,6 +566,50 @@ func (h *FlagHandler) Fill(
 	firstFirst bool,
 	combineObjects bool,
 ) (bool, error) {
+	if combines(t, combineObjects) {
+		return false, nil
+	}
+	return h.fill("", t, v, tag)
+}
+
+func (h *FlagHandler) fillWhole(t reflect.Type, tag reflectutils.Tag) (reflect.Value, bool, error) {
+	return fillTemporary(t, func(v reflect.Value) (bool, error) {
+		return h.fill("", t, v, tag)
+	})
+}
+
+// Len is part of the CanLenFiller contract.  When combining, slices
+// are filled through Len so that they can be merged with values from
+// other fillers.
+func (h *FlagHandler) Len(
+	t reflect.Type,
+	tag reflectutils.Tag,
+	firstFirst bool,
+	combineObjects bool,
+) (int, bool) {
+	return lenThroughFill(h, t, tag)
+}
+
+// Keys is part of the CanKeysFiller contract.  When combining, maps
+// are filled through Keys so that they can be merged with values from
+// other fillers.
+func (h *FlagHandler) Keys(
+	t reflect.Type,
+	tag reflectutils.Tag,
+	firstFirst bool,
+	combineObjects bool,
+) ([]string, bool) {
+	return keysThroughFill(h, t, tag)
+}
+
+// fill is Fill for flags whose names are prefixed because they
+// are inside flag groups
+func (h *FlagHandler) fill(
+	prefix string,
+	t reflect.Type,
+	v reflect.Value,
+	tag reflectutils.Tag,
+) (filled bool, err error) {
 	h.debugf("fill %s %s %s", tag.Tag, tag.Value, t)
 	if t.Kind() == reflect.Ptr {
 		h.debugf("fill skipping pointer")
@@ -294,10 +618,29 @@ func (h *FlagHandler) Fill(
 	if tag.Tag == "" {
 		return false, nil
 	}
+	if _, ok := h.flagGroupPrefix(t, tag); ok {
+		return false, nil
+	}
 	rawRef, setterType, nonPointerType, err := parseFlagRef(tag, t)
 	if err != nil {
 		return false, err
 	}
+	rawRef.addPrefix(prefix)
+	if rawRef.Content != "" {
+		// filled by withContent
+		return false, nil
+	}
+	if rawRef.Enum != "" {
+		defer func() {
+			if filled && err == nil {
+				err = checkEnum(rawRef.Enum, v)
+				if err != nil {
+					filled = false
+					err = commonerrors.UsageError(errors.Wrap(err, h.describeFlag(&rawRef)))
+				}
+			}
+		}()
+	}
 	var found bool
 	isMap := nonPointerType.Kind() == reflect.Map
 	for _, n := range rawRef.Name {
@@ -323,6 +666,12 @@ func (h *FlagHandler) Fill(
 			found = true
 			continue
 		}
+		if ref.FromFile {
+			err := h.readFromFiles(ref)
+			if err != nil {
+				return false, err
+			}
+		}
 		h.debugf("fill lookup %s %s %v", tag.Tag, tag.Value, t)
 		setter, ok := ref.setters[setterKey{
 			typ:   setterType,
@@ -420,6 +769,52 @@ func (h *FlagHandler) Fill(
 	return false, commonerrors.LibraryError(errors.New("missing prewalk"))
 }
 
+func (h *FlagHandler) content(t reflect.Type, tag reflectutils.Tag) (nflex.Source, bool, error) {
+	return h.prefixedContent("", t, tag)
+}
+
+// prefixedContent is content for flags within flag groups
+func (h *FlagHandler) prefixedContent(prefix string, t reflect.Type, tag reflectutils.Tag) (nflex.Source, bool, error) {
+	rawRef, _, _, err := parseFlagRef(tag, t)
+	if err != nil {
+		return nil, false, err
+	}
+	if rawRef.Content == "" {
+		return nil, false, nil
+	}
+	rawRef.addPrefix(prefix)
+	for _, n := range rawRef.Name {
+		var ref *flagRef
+		switch utf8.RuneCountInString(n) {
+		case 0:
+			continue
+		case 1:
+			ref = h.shortFlags[n]
+		default:
+			ref = h.longFlags[n]
+		}
+		if ref == nil {
+			return nil, false, commonerrors.LibraryError(errors.Errorf("internal error: Could not find pre-registered flagRef for %s", n))
+		}
+		if len(ref.values) == 0 {
+			continue
+		}
+		if ref.FromFile {
+			err := h.readFromFiles(ref)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		last := len(ref.values) - 1
+		source, err := decodeContent(ref.Content, ref.values[last])
+		if err != nil {
+			return nil, false, commonerrors.UsageError(errors.Wrap(err, ref.used[last]))
+		}
+		return source, true, nil
+	}
+	return nil, false, nil
+}
+
 func parseFlagRef(tag reflectutils.Tag, t reflect.Type) (flagRef, reflect.Type, reflect.Type, error) {
 	ref := flagRef{
 		flagTag: flagTag{
@@ -446,6 +841,16 @@ func parseFlagRef(tag reflectutils.Tag, t reflect.Type) (flagRef, reflect.Type,
 		setterType = nonPointerType.Elem()
 	}
 	err := tag.Fill(&ref)
+	if ref.Content != "" {
+		// the value is a single document, regardless of type
+		ref.isBool = false
+		ref.isSlice = false
+		ref.isMap = false
+		ref.IsCounter = false
+		ref.Map = ""
+		ref.Split = ""
+		return ref, setterType, nonPointerType, err
+	}
 	switch ref.Split {
 	case "none":
 		ref.Split = ""
@@ -485,13 +890,22 @@ func (h *FlagHandler) PreWalk(tagName string, model interface{}) error {
 		if tag.Tag == "" {
 			return true
 		}
+		if _, ok := h.flagGroupPrefix(f.Type, tag); ok {
+			return true
+		}
 		ref, setterType, _, err := parseFlagRef(tag, f.Type)
 		if err != nil {
 			walkErr = err
 			return true
 		}
+		prefix := h.groupPrefix(tagName, v.Type(), f.Index)
+		ref.addPrefix(prefix)
 		ref.fieldName = f.Name
-		h.rawData = append(h.rawData, f)
+		ref.targets = []flagTarget{{
+			model: model,
+			path:  fieldPath(v.Type(), f.Index),
+		}}
+		h.rawData = append(h.rawData, flagField{StructField: f, prefix: prefix})
 		if ref.isMap {
 			if ref.Split != "=" && ref.Map == "prefix" {
 				walkErr = commonerrors.ProgrammerError(errors.New("map=prefix requires split=equals"))
@@ -503,10 +917,17 @@ func (h *FlagHandler) PreWalk(tagName string, model interface{}) error {
 				walkErr = commonerrors.ProgrammerError(errors.Errorf("map=%s not defined, map=explode|prefix", ref.Map))
 			}
 		}
-		h.debugf("PreWalk make setter %s was %s", setterType, f.Type)
-		setter, err := reflectutils.MakeStringSetter(setterType, reflectutils.WithSplitOn(ref.Split))
-		if err != nil {
-			walkErr = commonerrors.UsageError(errors.Wrap(err, f.Name))
+		var registered bool
+		var setter func(reflect.Value, string) error
+		if ref.Content == "" {
+			h.debugf("PreWalk make setter %s was %s", setterType, f.Type)
+			setter, err = reflectutils.MakeStringSetter(setterType, reflectutils.WithSplitOn(ref.Split))
+			if err != nil {
+				walkErr = commonerrors.UsageError(errors.Wrap(err, f.Name))
+				return true
+			}
+		} else if err := checkContent(ref.Content); err != nil {
+			walkErr = errors.Wrap(err, f.Name)
 			return true
 		}
 		for _, n := range ref.Name {
@@ -542,12 +963,17 @@ func (h *FlagHandler) PreWalk(tagName string, model interface{}) error {
 				}
 				existing.isBool = existing.isBool && ref.isBool
 				existing.setters[sk] = setter
+				existing.targets = append(existing.targets, ref.targets...)
 			} else {
 				h.debug("prewalk new flag registration")
 				ref.setters = map[setterKey]func(reflect.Value, string) error{
 					sk: setter,
 				}
 				(*m)[n] = &ref
+				if ref.Required && !registered {
+					h.requiredRefs = append(h.requiredRefs, &ref)
+					registered = true
+				}
 			}
 		}
 		return true
@@ -0,0 +1,199 @@
+package nfigure
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/muir/commonerrors"
+	"github.com/pkg/errors"
+)
+
+const defaultResponseFileDepth = 10
+
+// ResponseFiles turns on expansion of "@path" arguments.  Before parsing,
+// each argument that starts with "@" is replaced by the arguments found in
+// the file at path.  This avoids limits on the length of command lines.
+//
+// The file is split into arguments following shell-like rules: arguments are
+// separated by whitespace; single quotes preserve everything up to the next
+// single quote; double quotes preserve everything except that backslash
+// escapes a double quote, backslash, dollar, or backquote; outside of quotes,
+// backslash escapes the next character, except that a backslash at the end
+// of a line joins it to the next line; and "#" at the start of an argument
+// begins a comment that runs to the end of the line.  No other expansions
+// are done.
+//
+// Response files can include other response files.  Relative paths in
+// a response file are relative to the directory of the file that includes them.
+// Inclusion stops with an error at maxDepth levels.  If maxDepth is zero, 10 is used.
+//
+// Arguments after "--" on the command line are not expanded.
+//
+// Errors for flags found in response files mention the file.
+func ResponseFiles(maxDepth int) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		if maxDepth <= 0 {
+			maxDepth = defaultResponseFileDepth
+		}
+		h.responseFileDepth = maxDepth
+		return nil
+	}
+}
+
+// expandResponseFiles replaces h.args with its expansion.  It remembers
+// the original arguments so that it can be invoked again.
+func (h *FlagHandler) expandResponseFiles() error {
+	if h.responseFileDepth == 0 {
+		return nil
+	}
+	if h.unexpandedArgs == nil {
+		h.unexpandedArgs = h.args
+	}
+	args := []string{h.unexpandedArgs[0]}
+	origins := []string{""}
+	var done bool
+	for _, arg := range h.unexpandedArgs[1:] {
+		if arg == "--" {
+			done = true
+		}
+		if done || !isResponseFile(arg) {
+			args = append(args, arg)
+			origins = append(origins, "")
+			continue
+		}
+		var err error
+		args, origins, err = h.expandResponseFile(args, origins, arg[1:], "", 1)
+		if err != nil {
+			return err
+		}
+	}
+	h.args = args
+	h.argOrigins = origins
+	return nil
+}
+
+func (h *FlagHandler) expandResponseFile(args []string, origins []string, path string, from string, depth int) ([]string, []string, error) {
+	if depth > h.responseFileDepth {
+		return nil, nil, commonerrors.UsageError(errors.Errorf("response file %s: nested more than %d levels deep", path, h.responseFileDepth))
+	}
+	if from != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	h.debugf("expanding response file %s", path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, commonerrors.UsageError(errors.Wrap(err, "response file"))
+	}
+	tokens, err := splitResponseFile(string(data))
+	if err != nil {
+		return nil, nil, commonerrors.UsageError(errors.Wrapf(err, "response file %s", path))
+	}
+	for _, token := range tokens {
+		if isResponseFile(token) {
+			args, origins, err = h.expandResponseFile(args, origins, token[1:], path, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		args = append(args, token)
+		origins = append(origins, path)
+	}
+	return args, origins, nil
+}
+
+func isResponseFile(arg string) bool {
+	return len(arg) > 1 && arg[0] == '@'
+}
+
+// fromFile describes where argument i came from if it is from a response file
+func (h *FlagHandler) fromFile(i int) string {
+	if i < len(h.argOrigins) && h.argOrigins[i] != "" {
+		return " (from @" + h.argOrigins[i] + ")"
+	}
+	return ""
+}
+
+// blameResponseFile adds the name of the response file to errors caused
+// by argument i
+func (h *FlagHandler) blameResponseFile(i int, err error) error {
+	if i < len(h.argOrigins) && h.argOrigins[i] != "" {
+		return errors.Wrapf(err, "response file %s", h.argOrigins[i])
+	}
+	return err
+}
+
+// splitResponseFile breaks up the contents of a response file into
+// arguments using shell-like quoting.
+func splitResponseFile(s string) ([]string, error) {
+	var tokens []string
+	var b strings.Builder
+	var inToken bool
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inToken {
+				tokens = append(tokens, b.String())
+				b.Reset()
+				inToken = false
+			}
+		case r == '#' && !inToken:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '\\':
+			i++
+			if i == len(runes) {
+				return nil, errors.New("backslash at end of file")
+			}
+			if i+1 < len(runes) && runes[i] == '\r' && runes[i+1] == '\n' {
+				i++
+			}
+			if runes[i] == '\n' {
+				// line continuation, which does not start a token
+				continue
+			}
+			inToken = true
+			b.WriteRune(runes[i])
+		case r == '\'':
+			inToken = true
+			end := indexRune(runes, i+1, '\'')
+			if end == -1 {
+				return nil, errors.New("unterminated single quote")
+			}
+			b.WriteString(string(runes[i+1 : end]))
+			i = end
+		case r == '"':
+			inToken = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+		default:
+			inToken = true
+			b.WriteRune(r)
+		}
+	}
+	if inToken {
+		tokens = append(tokens, b.String())
+	}
+	return tokens, nil
+}
+
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
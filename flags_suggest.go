@@ -0,0 +1,253 @@
+package nfigure
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/reflectutils"
+	"github.com/pkg/errors"
+)
+
+const defaultSuggestDistance = 2
+
+// DidYouMean sets the maximum edit distance used when suggesting
+// alternatives for unknown flags and subcommands.  The default is 2.
+// Use 0 to turn suggestions off.
+//
+// Suggestions for flags are drawn from the whole subcommand chain: if a flag
+// is defined by a parent command or by a subcommand, the suggestion says so.
+//
+// Unknown words are normally positional arguments.  DidYouMean also turns on
+// checking them: if the FlagHandler has subcommands, a positional argument that
+// is not a subcommand but is within maxDistance of one is reported as a UsageError
+// rather than ending flag parsing.
+func DidYouMean(maxDistance int) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.suggestDistance = maxDistance
+		h.suggestSubcommands = maxDistance > 0
+		return nil
+	}
+}
+
+type suggestion struct {
+	name     string
+	isPrefix bool // map=prefix flags
+	scope    string
+}
+
+// unknownFlagError is used when "dash"+"flag" is not a known flag.
+func (h *FlagHandler) unknownFlagError(dash string, flag string) error {
+	err := errors.Errorf("Flag %s%s not defined", dash, flag)
+	if suggestions := h.suggestFlags(flag); len(suggestions) != 0 {
+		names := make([]string, len(suggestions))
+		for i, s := range suggestions {
+			names[i] = dash + s.name
+			if s.scope != "" {
+				names[i] += " (for " + s.scope + ")"
+			}
+		}
+		err = errors.Errorf("Flag %s%s not defined, did you mean %s?", dash, flag, strings.Join(names, " or "))
+	}
+	return commonerrors.UsageError(err)
+}
+
+// unknownSubcommandError returns nil unless word is close to, but not
+// the same as, one of the subcommands.
+func (h *FlagHandler) unknownSubcommandError(word string) error {
+	if !h.suggestSubcommands || len(h.subcommands) == 0 || strings.HasPrefix(word, "-") {
+		return nil
+	}
+	candidates := make([]suggestion, 0, len(h.subcommandsOrder))
+	for _, name := range h.subcommandsOrder {
+		candidates = append(candidates, suggestion{name: name})
+		for _, alias := range h.subcommands[name].aliases {
+			candidates = append(candidates, suggestion{name: alias})
+		}
+	}
+	found := closest(word, candidates, h.suggestDistance)
+	if len(found) == 0 {
+		return nil
+	}
+	names := make([]string, len(found))
+	for i, s := range found {
+		names[i] = s.name
+	}
+	return commonerrors.UsageError(errors.Errorf("Unknown subcommand %s, did you mean %s?", word, strings.Join(names, " or ")))
+}
+
+func (h *FlagHandler) suggestFlags(flag string) []suggestion {
+	if h.suggestDistance <= 0 {
+		return nil
+	}
+	var candidates []suggestion
+	candidates = append(candidates, h.flagCandidates("")...)
+	for p := h.Parent; p != nil; p = p.Parent {
+		candidates = append(candidates, p.flagCandidates(p.commandPath())...)
+	}
+	candidates = append(candidates, h.subcommandFlagCandidates()...)
+	return closest(flag, candidates, h.suggestDistance)
+}
+
+// flagCandidates lists the long flags that are currently known.  Short flags
+// are not considered because everything is close to a single letter.
+func (h *FlagHandler) flagCandidates(scope string) []suggestion {
+	candidates := make([]suggestion, 0, len(h.longFlags)+len(h.mapFlags))
+	for name, ref := range h.longFlags {
+		if ref.Hidden {
+			continue
+		}
+		candidates = append(candidates, suggestion{name: name, scope: scope})
+		if h.negativeNo && ref.isBool {
+			candidates = append(candidates, suggestion{name: "no-" + name, scope: scope})
+		}
+	}
+	for name, ref := range h.mapFlags {
+		if ref.Hidden {
+			continue
+		}
+		candidates = append(candidates, suggestion{name: name, scope: scope, isPrefix: true})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].name < candidates[j].name
+	})
+	return candidates
+}
+
+// subcommandFlagCandidates lists flags defined for subcommands.  Subcommands
+// that have not been selected have not been through PreWalk so their models
+// are examined directly.
+func (h *FlagHandler) subcommandFlagCandidates() []suggestion {
+	var candidates []suggestion
+	for _, name := range h.subcommandsOrder {
+		sub := h.subcommands[name]
+		if h.selectedSubcommand == name {
+			continue
+		}
+		scope := sub.commandPath()
+		candidates = append(candidates, sub.flagCandidates(scope)...)
+		if sub.configModel != nil {
+			reflectutils.WalkStructElements(reflect.TypeOf(sub.configModel), func(f reflect.StructField) bool {
+				tag := reflectutils.SplitTag(f.Tag).Set().Get(h.tagName)
+				if tag.Tag == "" {
+					return true
+				}
+				if _, ok := sub.flagGroupPrefix(f.Type, tag); ok {
+					return true
+				}
+				ref, _, _, err := parseFlagRef(tag, f.Type)
+				if err != nil || ref.Hidden {
+					return true
+				}
+				ref.addPrefix(sub.groupPrefix(h.tagName, reflect.TypeOf(sub.configModel), f.Index))
+				for _, n := range ref.Name {
+					if utf8.RuneCountInString(n) < 2 {
+						continue
+					}
+					candidates = append(candidates, suggestion{
+						name:     n,
+						scope:    scope,
+						isPrefix: ref.isMap && ref.Map == "prefix",
+					})
+				}
+				return true
+			})
+		}
+		candidates = append(candidates, sub.subcommandFlagCandidates()...)
+	}
+	return candidates
+}
+
+// commandPath is the program name followed by the subcommands
+// needed to reach h
+func (h *FlagHandler) commandPath() string {
+	var path []string
+	for c := h; c.Parent != nil; c = c.Parent {
+		for _, name := range c.Parent.subcommandsOrder {
+			if c.Parent.subcommands[name] == c {
+				path = append([]string{name}, path...)
+				break
+			}
+		}
+	}
+	var root *FlagHandler
+	for root = h; root.Parent != nil; root = root.Parent {
+	}
+	if len(root.args) > 0 {
+		path = append([]string{filepath.Base(root.args[0])}, path...)
+	}
+	return strings.Join(path, " ")
+}
+
+// closest returns the candidates that are nearest to word as long as
+// they are within maxDistance.  Suggestions are not made when the distance
+// is as large as the candidate itself.
+func closest(word string, candidates []suggestion, maxDistance int) []suggestion {
+	best := maxDistance
+	var found []suggestion
+	seen := make(map[suggestion]struct{})
+	for _, c := range candidates {
+		compare := word
+		length := utf8.RuneCountInString(c.name)
+		var rest string
+		if c.isPrefix {
+			// map=prefix flags: compare only the prefix and then
+			// suggest the prefix with the rest of what was given
+			runes := []rune(word)
+			if len(runes) <= length {
+				continue
+			}
+			compare = string(runes[:length])
+			rest = string(runes[length:])
+			c.isPrefix = false
+		}
+		d := editDistance(compare, c.name)
+		c.name += rest
+		if c.scope == "" && d == 0 {
+			continue
+		}
+		if d > maxDistance || d >= length || d > best {
+			continue
+		}
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		if d < best {
+			best = d
+			found = found[:0]
+		}
+		found = append(found, c)
+	}
+	return found
+}
+
+// editDistance is the optimal string alignment distance: the Levenshtein
+// distance plus transposition of adjacent characters.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -2,7 +2,9 @@ package nfigure
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -61,6 +63,22 @@ type flagSet6 struct {
 	CP   *complex128   `flag:"cp"`
 }
 
+type flagSet7 struct {
+	User  string `flag:"u user,required"`
+	Depth int    `flag:"depth,required"`
+	Quiet bool   `flag:"q"`
+}
+
+type flagSet8 struct {
+	Target string `flag:"target,required"`
+}
+
+type flagSet9 struct {
+	Verbose bool   `flag:"verbose"`
+	Version bool   `flag:"version"`
+	Output  string `flag:"output o"`
+}
+
 type importBool struct {
 	name string
 	dflt bool
@@ -503,6 +521,223 @@ var cases = []flagTestCase{
 			this is additional help text
 			`),
 	},
+	{
+		base: &flagSet7{},
+		cmd:  "-u fred --depth 3",
+		want: &flagSet7{
+			User:  "fred",
+			Depth: 3,
+		},
+	},
+	{
+		base:  &flagSet7{},
+		cmd:   "-q",
+		want:  &flagSet7{},
+		error: "Missing required flag(s): -u/--user, --depth",
+	},
+	{
+		name:    "goFlags required",
+		base:    &flagSet7{},
+		cmd:     "-depth 3",
+		goFlags: true,
+		want:    &flagSet7{},
+		error:   "Missing required flag(s): -u/--user",
+	},
+	{
+		name: "required in unselected subcommand",
+		base: &flagSet3{},
+		cmd:  "-p 20 xy",
+		want: &flagSet3{
+			P: pointer.To(int32(20)),
+		},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet8{},
+		},
+		remaining: []string{"xy"},
+	},
+	{
+		name: "required in selected subcommand",
+		base: &flagSet7{},
+		cmd:  "-u fred foo",
+		want: &flagSet7{},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet8{},
+		},
+		sub:   "foo",
+		error: "Missing required flag(s): --depth, --target (foo)",
+	},
+	{
+		base:  &flagSet1{},
+		cmd:   "--iflg 3",
+		want:  &flagSet1{},
+		error: "Flag --iflg not defined, did you mean --iflag?",
+	},
+	{
+		base:  &flagSet1{},
+		cmd:   "--sa3=x",
+		want:  &flagSet1{},
+		error: "did you mean --sa1 or --sa2?",
+	},
+	{
+		base:  &flagSet2{},
+		cmd:   "--Nm2=xyz",
+		want:  &flagSet2{},
+		error: "did you mean --nm2?",
+	},
+	{
+		name: "suggest parent flag",
+		base: &flagSet3{},
+		cmd:  "foo --pflag=3",
+		want: &flagSet3{},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet1{},
+		},
+		error: "Flag --pflag not defined, did you mean --pflag (for ",
+	},
+	{
+		name: "suggest subcommand flag",
+		base: &flagSet3{},
+		cmd:  "--countr=3 foo",
+		want: &flagSet3{},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet1{},
+		},
+		error: " foo)?",
+	},
+	{
+		name: "suggest subcommand",
+		base: &flagSet3{},
+		cmd:  "-p 20 fo",
+		want: &flagSet3{},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet1{},
+		},
+		additionalArgs: []FlaghandlerOptArg{
+			DidYouMean(2),
+		},
+		error: "Unknown subcommand fo, did you mean foo?",
+	},
+	{
+		base: &flagSet9{},
+		cmd:  "--verb --out=x",
+		want: &flagSet9{
+			Verbose: true,
+			Output:  "x",
+		},
+		additionalArgs: []FlaghandlerOptArg{AllowAbbreviations()},
+	},
+	{
+		base: &flagSet9{Verbose: true},
+		cmd:  "--no-verb --vers",
+		want: &flagSet9{
+			Version: true,
+		},
+		additionalArgs: []FlaghandlerOptArg{AllowAbbreviations()},
+	},
+	{
+		base:           &flagSet9{},
+		cmd:            "--ver",
+		want:           &flagSet9{},
+		error:          "Flag --ver is ambiguous, it could be --verbose or --version",
+		additionalArgs: []FlaghandlerOptArg{AllowAbbreviations()},
+	},
+	{
+		name:  "abbreviations not enabled",
+		base:  &flagSet9{},
+		cmd:   "--verb",
+		want:  &flagSet9{},
+		error: "Flag --verb not defined",
+	},
+	{
+		name: "abbreviated subcommand",
+		base: &flagSet9{},
+		cmd:  "-o x fo -i 10",
+		want: &flagSet9{
+			Output: "x",
+		},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet1{},
+		},
+		sub: "foo",
+		wantSub: &flagSet1{
+			I: 10,
+		},
+		additionalArgs: []FlaghandlerOptArg{AllowAbbreviations()},
+	},
+	{
+		name: "ambiguous subcommand",
+		base: &flagSet9{},
+		cmd:  "f",
+		want: &flagSet9{},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet1{},
+			"fob": &flagSet1{},
+		},
+		error:          "Subcommand f is ambiguous, it could be ",
+		additionalArgs: []FlaghandlerOptArg{AllowAbbreviations()},
+	},
+	{
+		name: "permute",
+		base: &flagSet9{},
+		cmd:  "file1 --verbose file2 -o x file3",
+		want: &flagSet9{
+			Verbose: true,
+			Output:  "x",
+		},
+		remaining:      []string{"file1", "file2", "file3"},
+		additionalArgs: []FlaghandlerOptArg{PermuteArgs()},
+	},
+	{
+		name: "permute double dash",
+		base: &flagSet9{},
+		cmd:  "a --verbose -- --version b",
+		want: &flagSet9{
+			Verbose: true,
+		},
+		remaining:      []string{"a", "--version", "b"},
+		additionalArgs: []FlaghandlerOptArg{PermuteArgs()},
+	},
+	{
+		name:  "permute no positional",
+		base:  &flagSet9{},
+		cmd:   "a --verbose",
+		want:  &flagSet9{},
+		error: "unexpected positional",
+		additionalArgs: []FlaghandlerOptArg{
+			PermuteArgs(),
+			NoPositional(),
+		},
+	},
+	{
+		name: "permute subcommand",
+		base: &flagSet9{},
+		cmd:  "-o x foo a -i 10 b",
+		want: &flagSet9{
+			Output: "x",
+		},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet1{},
+		},
+		sub: "foo",
+		wantSub: &flagSet1{
+			I: 10,
+		},
+		remaining:      []string{"a", "b"},
+		additionalArgs: []FlaghandlerOptArg{PermuteArgs()},
+	},
+	{
+		name: "permute first positional selects subcommand",
+		base: &flagSet9{},
+		cmd:  "a foo --verbose",
+		want: &flagSet9{
+			Verbose: true,
+		},
+		subcommands: map[string]interface{}{
+			"foo": &flagSet1{},
+		},
+		remaining:      []string{"a", "foo"},
+		additionalArgs: []FlaghandlerOptArg{PermuteArgs()},
+	},
 	{
 		base: &flagSet3{},
 		importBools: []importBool{
@@ -721,6 +956,222 @@ func deindent(s string) string {
 	return re.ReplaceAllLiteralString(s, "")
 }
 
+func TestRequiredSatisfiedBy(t *testing.T) {
+	t.Setenv("NF_TEST_USER", "env-user")
+	type options struct {
+		User  string `flag:"user,required" env:"NF_TEST_USER"`
+		Depth int    `flag:"depth,required" default:"3"`
+	}
+	cases := []struct {
+		name  string
+		args  []string
+		opts  []FlaghandlerOptArg
+		error string
+	}{
+		{
+			name:  "not allowed",
+			args:  []string{"--depth", "4"},
+			error: "Missing required flag(s): --user",
+		},
+		{
+			name: "env allowed",
+			args: []string{"--depth", "4"},
+			opts: []FlaghandlerOptArg{RequiredSatisfiedBy("env", "config")},
+		},
+		{
+			name:  "default not allowed",
+			args:  []string{},
+			opts:  []FlaghandlerOptArg{RequiredSatisfiedBy("env", "config")},
+			error: "Missing required flag(s): --depth",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts options
+			registry := NewRegistry(WithFiller("flag", PosixFlagHandler(append(tc.opts, WithArgs(tc.args))...)))
+			require.NoError(t, registry.Request(&opts))
+			err := registry.Configure()
+			if tc.error != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.error)
+					assert.True(t, commonerrors.IsUsageError(err), "is usage error")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "env-user", opts.User)
+			assert.Equal(t, 4, opts.Depth)
+		})
+	}
+}
+
+func TestRequiredSatisfiedByCombined(t *testing.T) {
+	t.Setenv("NF_TEST_TAGS", "e|f")
+	type options struct {
+		Tags   []string          `flag:"tag,required" env:"NF_TEST_TAGS,split=|"`
+		Labels map[string]string `flag:"label,required,map=explode,split=equal" config:"labels"`
+	}
+	registry := NewRegistry(
+		WithFiller("flag", PosixFlagHandler(RequiredSatisfiedBy("env", "config"), WithArgs(nil))),
+		WithFiller("config", NewFileFiller()))
+	require.NoError(t, registry.ConfigMap(map[string]any{"labels": map[string]string{"a": "b"}}))
+	var opts options
+	require.NoError(t, registry.Request(&opts))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, []string{"e", "f"}, opts.Tags)
+	assert.Equal(t, map[string]string{"a": "b"}, opts.Labels)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("verbose", "verbose"))
+	assert.Equal(t, 1, editDistance("verbos", "verbose"))
+	assert.Equal(t, 1, editDistance("vebrose", "verbose"), "transposition")
+	assert.Equal(t, 3, editDistance("", "foo"))
+}
+
+func TestSplitResponseFile(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []string
+		error string
+	}{
+		{input: "-a b\n\t--c=d  ", want: []string{"-a", "b", "--c=d"}},
+		{input: `--name 'two words' "say \"hi\"" x\ y`, want: []string{"--name", "two words", `say "hi"`, "x y"}},
+		{input: "# comment\n-v # another\n-w", want: []string{"-v", "-w"}},
+		{input: `''`, want: []string{""}},
+		{input: "--a \\\n  --b", want: []string{"--a", "--b"}},
+		{input: "--a \\\r\n  --b", want: []string{"--a", "--b"}},
+		{input: "x\\\ny", want: []string{"xy"}},
+		{input: `"abc`, error: "unterminated double quote"},
+		{input: `'abc`, error: "unterminated single quote"},
+	}
+	for _, tc := range cases {
+		got, err := splitResponseFile(tc.input)
+		if tc.error != "" {
+			if assert.Error(t, err, tc.input) {
+				assert.Contains(t, err.Error(), tc.error, tc.input)
+			}
+			continue
+		}
+		require.NoError(t, err, tc.input)
+		assert.Equal(t, tc.want, got, tc.input)
+	}
+}
+
+func TestResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	write("inner.rsp", "--version\n")
+	outer := write("outer.rsp", "--output 'a b' @inner.rsp\n")
+	bad := write("bad.rsp", "--verbose --outptu x\n")
+	loop := write("loop.rsp", "@loop.rsp\n")
+
+	run := func(args ...string) (flagSet9, []string, error) {
+		var got flagSet9
+		var remaining []string
+		fh := PosixFlagHandler(ResponseFiles(0), WithArgs(args), OnStart(func(args []string) {
+			remaining = args
+		}))
+		registry := NewRegistry(WithFiller("flag", fh))
+		require.NoError(t, registry.Request(&got))
+		err := registry.Configure()
+		return got, remaining, err
+	}
+
+	got, remaining, err := run("--verbose", "@"+outer, "x", "--", "@"+outer)
+	require.NoError(t, err)
+	assert.Equal(t, flagSet9{Verbose: true, Version: true, Output: "a b"}, got)
+	assert.Equal(t, []string{"x", "--", "@" + outer}, remaining)
+
+	_, _, err = run("@" + bad)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "response file "+bad+": Flag --outptu not defined")
+		assert.True(t, commonerrors.IsUsageError(err), "is usage error")
+	}
+
+	_, _, err = run("@" + loop)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "nested more than 10 levels deep")
+	}
+
+	_, _, err = run("@" + filepath.Join(dir, "missing.rsp"))
+	assert.Error(t, err)
+}
+
+func TestResponseFileValueErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "args.rsp")
+	require.NoError(t, os.WriteFile(path, []byte("--depth ten"), 0o600))
+	var got struct {
+		Depth int `flag:"depth"`
+	}
+	registry := NewRegistry(WithFiller("flag", PosixFlagHandler(ResponseFiles(0), WithArgs([]string{"@" + path}))))
+	require.NoError(t, registry.Request(&got))
+	err := registry.Configure()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "--depth (from @"+path+")")
+	}
+}
+
+type captureLogger struct {
+	lines []string
+}
+
+func (c *captureLogger) Logf(format string, a ...any) {
+	c.lines = append(c.lines, fmt.Sprintf(format, a...))
+}
+
+func TestDeprecatedAndHiddenFlags(t *testing.T) {
+	type options struct {
+		Timeout string `flag:"timeout" help:"how long to wait"`
+		Old     string `flag:"tmo,deprecated=use --timeout,replacedBy=timeout"`
+		Secret  bool   `flag:"secret,hidden"`
+	}
+	cases := []struct {
+		cmd      string
+		want     options
+		warnings []string
+	}{
+		{
+			cmd:      "--tmo 5",
+			want:     options{Timeout: "5", Old: "5"},
+			warnings: []string{"Flag --tmo is deprecated: use --timeout"},
+		},
+		{
+			cmd:      "--tmo=5 --timeout 6",
+			want:     options{Timeout: "6", Old: "5"},
+			warnings: []string{"Flag --tmo is deprecated: use --timeout"},
+		},
+		{
+			cmd:      "--timeout 6 --tmo 5 --secret",
+			want:     options{Timeout: "5", Old: "5", Secret: true},
+			warnings: []string{"Flag --tmo is deprecated: use --timeout"},
+		},
+		{
+			cmd:  "--timeout 6",
+			want: options{Timeout: "6"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.cmd, func(t *testing.T) {
+			var logger captureLogger
+			var got options
+			fh := PosixFlagHandler(WarningLogger(&logger), WithArgs(strings.Split(tc.cmd, " ")))
+			registry := NewRegistry(WithFiller("flag", fh))
+			require.NoError(t, registry.Request(&got))
+			require.NoError(t, registry.Configure())
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.warnings, logger.lines)
+			usage := fh.Usage()
+			assert.NotContains(t, usage, "secret")
+			assert.Contains(t, usage, "set Old (string) (deprecated: use --timeout)")
+		})
+	}
+}
+
 func TestReproduceFlagsPanic(t *testing.T) {
 	t.Log("this test reproduces a problem that caused a panic")
 	type options struct {
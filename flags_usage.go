@@ -0,0 +1,554 @@
+package nfigure
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/reflectutils"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// UsageModel is the information that Usage() renders.  It is
+// available from FlagHandler.UsageModel() for programs that want to
+// produce their own help output.  It is also what templates provided
+// with UsageTemplate are executed against.
+type UsageModel struct {
+	Program     string // program name, eg: os.Args[0]
+	Synopsis    string // the first line of the usage message, without "Usage: "
+	Sections    []UsageSection
+	Subcommands []UsageSubcommand
+	HelpTopics  []UsageHelpTopic
+	HelpText    string // from WithHelpText
+	Width       int    // 0 if output should not be wrapped
+}
+
+// UsageSection is a group of flags.  Flags without a "section=" in their
+// flag tag are in the first section, "Options".
+type UsageSection struct {
+	Name  string
+	Flags []UsageFlag
+}
+
+// UsageFlag describes one flag.  Hidden flags are not included.
+type UsageFlag struct {
+	Names      []string // as given in the flag tag
+	Syntax     string   // eg: "[--host=Hosts&Hosts...]"
+	Alternates string   // other names for the same flag, eg: "[-h Hosts&Hosts...]"
+	Help       string
+	Field      string // the name of the struct field, empty for imported flags
+	Type       string // the type of the struct field, empty for imported flags
+	Required   bool
+	Deprecated bool
+	Enum       []string
+
+	// Default, Env, and Config are from the other tags on the same field.
+	Default string
+	Env     string
+	Config  string
+
+	// Annotations are the ones selected with UsageShowDefaults,
+	// UsageShowEnv, and UsageShowConfig, eg: "[default: 8080]"
+	Annotations []string
+}
+
+// UsageSubcommand describes one subcommand
+type UsageSubcommand struct {
+	Name    string
+	Aliases []string // from SubcommandAliases
+	Summary string
+}
+
+// UsageHelpTopic describes a topic added with HelpTopic
+type UsageHelpTopic struct {
+	Name    string
+	Summary string
+}
+
+const defaultUsageSection = "Options"
+
+// UsageWidth sets the width that the usage message is wrapped to.
+// By default, the width of the terminal is used if standard output is
+// a terminal, otherwise $COLUMNS is used if set, otherwise the usage
+// message is not wrapped.  Use a negative width to turn off wrapping.
+func UsageWidth(width int) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.usageWidth = width
+		return nil
+	}
+}
+
+// UsageShowDefaults controls if the usage message notes the default value
+// of flags, eg: "[default: 8080]".  Defaults come from the "default" tag
+// or the tag set with WithDefaultsTag.  This is on by default.
+func UsageShowDefaults(show bool) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.showDefaults = show
+		return nil
+	}
+}
+
+// UsageShowEnv controls if the usage message notes the environment
+// variable that can also provide the value of a flag, eg: "[env: PORT]".
+// The variable comes from the tag used for the registry's NewEnvFiller,
+// usually "env".  This is on by default.
+func UsageShowEnv(show bool) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.showEnv = show
+		return nil
+	}
+}
+
+// UsageShowConfig controls if the usage message notes the configuration
+// file key that can also provide the value of a flag, eg: "[config: port]".
+// The key comes from the tag used for the registry's FileFiller, usually
+// "config".  This is off by default.
+func UsageShowConfig(show bool) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		h.showConfig = show
+		return nil
+	}
+}
+
+// UsageTemplate replaces the layout of the usage message with a
+// text/template that is executed with a UsageModel.  In addition to the
+// standard template functions, the following are available:
+//
+//	wrap width indent text -- indent text and wrap it to width
+//	pad width text -- add spaces after text to make it width long
+//	join separator list -- strings.Join
+//
+// For example:
+//
+//	Usage: {{.Synopsis}}
+//	{{range .Sections}}{{.Name}}:
+//	{{range .Flags}}{{wrap $.Width 2 .Syntax}}
+//	{{wrap $.Width 8 .Help}}
+//	{{end}}{{end}}
+func UsageTemplate(text string) FlaghandlerOptArg {
+	return func(h *FlagHandler) error {
+		t, err := template.New("usage").Funcs(usageFuncs).Parse(text)
+		if err != nil {
+			return commonerrors.ProgrammerError(errors.Wrap(err, "usage template"))
+		}
+		h.usageTemplate = t
+		return nil
+	}
+}
+
+var usageFuncs = template.FuncMap{
+	"wrap": func(width int, indent int, text string) string {
+		return wrapLine(strings.Repeat(" ", indent)+text, indent, width)
+	},
+	"pad": func(width int, text string) string {
+		return fmt.Sprintf("%-*s", width, text)
+	},
+	"join": func(sep string, list []string) string {
+		return strings.Join(list, sep)
+	},
+}
+
+// Usage produces a usage summary.  It is not called automatically unless
+// WithHelpText is used in creation of the flag handler.
+func (h *FlagHandler) Usage() string {
+	return h.renderUsage(h.UsageModel())
+}
+
+func (h *FlagHandler) renderUsage(model UsageModel) string {
+	if h.usageTemplate != nil {
+		var b strings.Builder
+		err := h.usageTemplate.Execute(&b, model)
+		if err == nil {
+			return b.String()
+		}
+		h.warnf("usage template: %s", err)
+	}
+	return model.String()
+}
+
+// UsageModel returns the information needed to produce a usage message.
+func (h *FlagHandler) UsageModel() UsageModel {
+	// flags : -x
+	// options : -y foo
+	// parameters : --ything foo OR --ything=foo
+	// required: anything required
+
+	required := make([][]*opt, lastOpt)
+	optional := make([][]*opt, lastOpt)
+	groups := []string{""}
+	seenGroup := map[string]bool{"": true}
+
+	for _, f := range h.rawData {
+		tagSet := reflectutils.SplitTag(f.Tag).Set()
+		ref, _, _, err := parseFlagRef(tagSet.Get(h.tagName), f.Type)
+		if err != nil {
+			panic(err.Error())
+		}
+		ref.addPrefix(f.prefix)
+		if ref.Hidden {
+			continue
+		}
+		help := tagSet.Get(h.helpTag).Value
+		group := ref.Section
+		if help == "" {
+			help = fmt.Sprintf("set %s (%s)", f.Name, f.Type)
+		}
+		if deprecated, message := ref.deprecation(); deprecated {
+			help += " (deprecated" + prependColon(message) + ")"
+		}
+		dflt, env, config := h.otherSources(f.StructField, tagSet)
+		var notes []string
+		if h.showDefaults && dflt != "" {
+			notes = append(notes, "[default: "+dflt+"]")
+		}
+		if h.showEnv && env != "" {
+			notes = append(notes, "[env: "+env+"]")
+		}
+		if h.showConfig && config != "" {
+			notes = append(notes, "[config: "+config+"]")
+		}
+		if !seenGroup[group] {
+			seenGroup[group] = true
+			groups = append(groups, group)
+		}
+		nonPointer := reflectutils.NonPointer(f.Type)
+		var lead *opt
+		for i, n := range ref.Name {
+			o := &opt{
+				name:       n,
+				help:       help,
+				group:      group,
+				dflt:       dflt,
+				env:        env,
+				config:     config,
+				notes:      notes,
+				f:          f.StructField,
+				primary:    i == 0,
+				ref:        ref,
+				nonPointer: nonPointer,
+			}
+			if i == 0 {
+				lead = o
+			} else {
+				lead.alts = append(lead.alts, o)
+			}
+
+			o.category = getCategory(n, ref)
+
+			if ref.Required {
+				required[o.category] = append(required[o.category], o)
+				break
+			}
+			optional[o.category] = append(optional[o.category], o)
+		}
+	}
+	// This is a non-overlapping set with h.rawData
+	for _, ref := range h.imported {
+		help := ref.imported.Usage
+		if ref.imported.DefValue != "" {
+			help += fmt.Sprintf(" (defaults to %s)", ref.imported.DefValue)
+		}
+		o := &opt{
+			name:    ref.imported.Name,
+			help:    help,
+			dflt:    ref.imported.DefValue,
+			primary: true,
+			ref:     *ref,
+		}
+		o.category = getCategory(ref.Name[0], *ref)
+		optional[o.category] = append(optional[o.category], o)
+	}
+
+	model := UsageModel{
+		Program: h.args[0],
+		Width:   h.wrapWidth(),
+	}
+	if h.helpText != nil {
+		model.HelpText = *h.helpText
+	}
+
+	synopsis := make([]string, 0, len(h.rawData)+10)
+	synopsis = append(synopsis, h.args[0])
+
+	switch len(optional[flagOpt]) {
+	case 0:
+	default:
+		if h.combineShort {
+			synopsis = append(synopsis, " [-flags]")
+		} else {
+			synopsis = append(synopsis, " [flags]")
+		}
+	}
+	synopsis = append(synopsis, h.formatOpts(required[flagOpt])...)
+
+	switch len(optional[optionOpt]) {
+	case 0:
+	default:
+		if h.combineShort {
+			synopsis = append(synopsis, " [-options args]")
+		} else {
+			synopsis = append(synopsis, " [options]")
+		}
+	}
+	synopsis = append(synopsis, h.formatOpts(required[optionOpt])...)
+
+	switch len(optional[parameterOpt]) {
+	case 0:
+	default:
+		synopsis = append(synopsis, " [parameters]")
+	}
+	synopsis = append(synopsis, h.formatOpts(required[parameterOpt])...)
+
+	switch len(h.subcommandsOrder) {
+	case 0:
+	case 1:
+		if !h.helpAlreadyAdded || h.subcommandsOrder[0] != "help" {
+			synopsis = append(synopsis, " "+strings.Join(h.subcommandsOrder, "|")+" ")
+		}
+	case 2, 3, 4, 5, 6, 7:
+		synopsis = append(synopsis, " "+strings.Join(h.subcommandsOrder, "|")+" ")
+	default:
+		synopsis = append(synopsis, " subcommand")
+	}
+
+	if h.positionalHelp != "" {
+		synopsis = append(synopsis, " ", h.positionalHelp)
+	}
+	model.Synopsis = strings.Join(synopsis, "")
+
+	sections := make(map[string]*UsageSection)
+	for _, group := range groups {
+		name := group
+		if name == "" {
+			name = defaultUsageSection
+		}
+		sections[group] = &UsageSection{Name: name}
+	}
+	for i := undefinedOpt; i < lastOpt; i++ {
+		for _, optSet := range [][]*opt{required[i], optional[i]} {
+			for _, opt := range optSet {
+				if !opt.primary {
+					continue
+				}
+				section := sections[opt.group]
+				section.Flags = append(section.Flags, opt.usageFlag(h.doubleDash))
+			}
+		}
+	}
+	for _, group := range groups {
+		if len(sections[group].Flags) != 0 {
+			model.Sections = append(model.Sections, *sections[group])
+		}
+	}
+
+	for _, subcmd := range h.subcommandsOrder {
+		model.Subcommands = append(model.Subcommands, UsageSubcommand{
+			Name:    subcmd,
+			Aliases: h.subcommands[subcmd].aliases,
+			Summary: h.subcommands[subcmd].usageSummary,
+		})
+	}
+	for _, topic := range h.helpTopics {
+		model.HelpTopics = append(model.HelpTopics, UsageHelpTopic{
+			Name:    topic.name,
+			Summary: topic.summary,
+		})
+	}
+	return model
+}
+
+func (o opt) usageFlag(doubleDash bool) UsageFlag {
+	deprecated, _ := o.ref.deprecation()
+	uf := UsageFlag{
+		Names:      o.ref.Name,
+		Syntax:     strings.TrimPrefix(o.format(doubleDash), " "),
+		Alternates: strings.TrimPrefix(o.formatAlts(doubleDash), " "),
+		Help:       o.help,
+		Required:   o.ref.Required,
+		Deprecated: deprecated,
+		Enum:       enumValues(o.ref.Enum),
+
+		Default:     o.dflt,
+		Env:         o.env,
+		Config:      o.config,
+		Annotations: o.notes,
+	}
+	if o.ref.imported == nil {
+		uf.Field = o.f.Name
+		uf.Type = o.f.Type.String()
+	} else {
+		uf.Names = []string{o.ref.imported.Name}
+	}
+	return uf
+}
+
+// String renders the usage message in the default layout
+func (m UsageModel) String() string {
+	var b strings.Builder
+	b.WriteString("Usage: " + m.Synopsis + "\n")
+	for _, section := range m.Sections {
+		b.WriteString("\n" + section.Name + ":\n")
+		for _, f := range section.Flags {
+			b.WriteString(wrapLine(fmt.Sprintf(
+				"     %-29s %s",
+				f.Syntax,
+				strings.Join(notEmpty(
+					prependSpace(f.Alternates),
+					prependSpace(strings.Join(notEmpty(append([]string{f.Help}, f.Annotations...)...), " ")),
+				), " ")), 36, m.Width))
+			b.WriteString("\n")
+		}
+	}
+	if len(m.Subcommands) > 0 {
+		b.WriteString("\nSubcommands:\n")
+		for _, subcmd := range m.Subcommands {
+			b.WriteString(wrapLine(fmt.Sprintf(
+				"    %-20s %s",
+				strings.Join(append([]string{subcmd.Name}, subcmd.Aliases...), ", "),
+				subcmd.Summary), 25, m.Width))
+			b.WriteString("\n")
+		}
+	}
+	if len(m.HelpTopics) > 0 {
+		b.WriteString("\nHelp topics:\n")
+		for _, topic := range m.HelpTopics {
+			b.WriteString(wrapLine(fmt.Sprintf(
+				"    %-20s %s",
+				topic.Name,
+				topic.Summary), 25, m.Width))
+			b.WriteString("\n")
+		}
+	}
+	if m.HelpText != "" {
+		b.WriteString("\n" + m.HelpText + "\n")
+	}
+	return b.String()
+}
+
+// otherSources finds the default value, environment variable, and config
+// key that can also provide the value for a flag.
+func (h *FlagHandler) otherSources(f reflect.StructField, tagSet reflectutils.TagSet) (dflt string, env string, config string) {
+	defaultTag := "default"
+	if h.defaultTag != "" {
+		defaultTag = h.defaultTag
+	}
+	firstWord := func(tag reflectutils.Tag) string {
+		name, _, _ := strings.Cut(tag.Value, ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	}
+	dflt = firstWord(tagSet.Get(defaultTag))
+	envTag, configTag := h.sourceTags()
+	if envTag != "" {
+		env = firstWord(tagSet.Get(envTag))
+	}
+	if configTag == "" {
+		return dflt, env, ""
+	}
+	if tag, ok := tagSet.Lookup(configTag); ok {
+		config = firstWord(tag)
+		if config == "" && tag.Value != "-" && !strings.HasPrefix(tag.Value, "-,") {
+			config = f.Name
+		}
+	} else if f.IsExported() {
+		config = f.Name
+	}
+	return dflt, env, config
+}
+
+// sourceTags finds the tags used by the registry for the environment
+// (a filler from NewEnvFiller) and for configuration files (a FileFiller).
+// Before the handler is attached to a registry, the tags that NewRegistry
+// uses are assumed.
+func (h *FlagHandler) sourceTags() (env string, config string) {
+	if h.registry == nil {
+		return "env", "config"
+	}
+	for _, tag := range h.registry.fillers.Order() {
+		switch filler := h.registry.fillers.m[tag].(type) {
+		case LookupFiller:
+			if filler.isEnv && env == "" {
+				env = tag
+			}
+		case FileFiller:
+			if config == "" {
+				config = tag
+			}
+		}
+	}
+	return env, config
+}
+
+func (h *FlagHandler) wrapWidth() int {
+	switch {
+	case h.usageWidth < 0:
+		return 0
+	case h.usageWidth > 0:
+		return h.usageWidth
+	}
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		width, _, err := term.GetSize(int(os.Stdout.Fd()))
+		if err == nil && width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}
+
+// wrapLine breaks line at spaces so that it fits within width.  Continuation
+// lines are indented.  Words longer than the available space are not broken.
+// A width of zero means no wrapping.
+func wrapLine(line string, indent int, width int) string {
+	if width <= 0 {
+		return line
+	}
+	runes := []rune(line)
+	var b strings.Builder
+	for len(runes) > width {
+		brk := -1
+		for i := width; i > indent; i-- {
+			if runes[i] == ' ' {
+				brk = i
+				break
+			}
+		}
+		if brk == -1 {
+			for i := width + 1; i < len(runes); i++ {
+				if runes[i] == ' ' {
+					brk = i
+					break
+				}
+			}
+		}
+		if brk == -1 {
+			break
+		}
+		end := brk
+		for end > 0 && runes[end-1] == ' ' {
+			end--
+		}
+		start := brk
+		for start < len(runes) && runes[start] == ' ' {
+			start++
+		}
+		if start == len(runes) {
+			runes = runes[:end]
+			break
+		}
+		b.WriteString(string(runes[:end]))
+		b.WriteString("\n")
+		runes = append([]rune(strings.Repeat(" ", indent)), runes[start:]...)
+	}
+	b.WriteString(string(runes))
+	return b.String()
+}
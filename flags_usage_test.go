@@ -0,0 +1,179 @@
+package nfigure
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type usageOptions struct {
+	Verbose bool   `flag:"verbose v" help:"say more, or less"`
+	Port    int    `flag:"port,required,section=Networking" help:"port to listen on"`
+	Host    string `flag:"host,section=Networking" help:"the name or address of the interface to listen on, which defaults to all interfaces"`
+	Level   string `flag:"level,enum=debug|info,section=Logging" help:"logging level"`
+	Secret  string `flag:"secret,hidden"`
+}
+
+func usageHandler(t *testing.T, opts ...FlaghandlerOptArg) *FlagHandler {
+	savedArgs := os.Args
+	t.Cleanup(func() { os.Args = savedArgs })
+	os.Args = []string{"prog"}
+	fh := PosixFlagHandler(append([]FlaghandlerOptArg{WithArgs([]string{"--port", "80"})}, opts...)...)
+	registry := NewRegistry(WithFiller("flag", fh))
+	var options usageOptions
+	require.NoError(t, registry.Request(&options))
+	require.NoError(t, registry.Configure())
+	return fh
+}
+
+func TestUsageSections(t *testing.T) {
+	fh := usageHandler(t, UsageWidth(-1))
+	assert.Equal(t, `Usage: prog [-flags] [parameters] --port=int
+
+Options:
+     [--[no-]verbose]               [-v]  say more, or less
+
+Networking:
+     --port=int                     port to listen on
+     [--host=Host]                  the name or address of the interface to listen on, which defaults to all interfaces
+
+Logging:
+     [--level={debug|info}]         logging level
+`, fh.Usage())
+}
+
+func TestUsageHelpTextNotParsed(t *testing.T) {
+	var options struct {
+		Admin bool `flag:"admin" help:"grant access, group=admins only"`
+	}
+	fh := PosixFlagHandler(WithArgs(nil), UsageWidth(-1))
+	registry := NewRegistry(WithFiller("flag", fh))
+	require.NoError(t, registry.Request(&options))
+	require.NoError(t, registry.Configure())
+	usage := fh.Usage()
+	assert.Contains(t, usage, "grant access, group=admins only\n")
+	assert.NotContains(t, usage, "admins only:")
+}
+
+func TestUsageWrapping(t *testing.T) {
+	fh := usageHandler(t, UsageWidth(70))
+	usage := fh.Usage()
+	assert.Contains(t, usage, "\n"+
+		"     [--host=Host]                  the name or address of the\n"+
+		"                                    interface to listen on, which\n"+
+		"                                    defaults to all interfaces\n")
+	for _, line := range strings.Split(usage, "\n") {
+		assert.LessOrEqual(t, len(line), 70, line)
+	}
+}
+
+func TestUsageTemplate(t *testing.T) {
+	fh := usageHandler(t, UsageWidth(30), UsageTemplate(
+		`{{.Program}}{{range .Sections}}
+{{.Name}}:{{range .Flags}}
+{{pad 10 (join "," .Names)}}{{.Type}}{{if .Required}} (required){{end}}
+{{wrap $.Width 4 .Help}}{{end}}{{end}}
+`))
+	assert.Equal(t, `prog
+Options:
+verbose,v bool
+    say more, or less
+Networking:
+port      int (required)
+    port to listen on
+host      string
+    the name or address of the
+    interface to listen on,
+    which defaults to all
+    interfaces
+Logging:
+level     string
+    logging level
+`, fh.Usage())
+}
+
+func TestUsageModel(t *testing.T) {
+	model := usageHandler(t).UsageModel()
+	require.Len(t, model.Sections, 3)
+	assert.Equal(t, "Logging", model.Sections[2].Name)
+	assert.Equal(t, UsageFlag{
+		Names:  []string{"level"},
+		Syntax: "[--level={debug|info}]",
+		Help:   "logging level",
+		Field:  "Level",
+		Type:   "string",
+		Enum:   []string{"debug", "info"},
+		Config: "Level",
+	}, model.Sections[2].Flags[0])
+}
+
+func TestUsageAnnotations(t *testing.T) {
+	type options struct {
+		Port int    `flag:"port" default:"8080" env:"PORT" config:"listen.port" help:"port to listen on"`
+		Name string `flag:"name" env:"-" config:"-"`
+	}
+	cases := []struct {
+		opts []FlaghandlerOptArg
+		want string
+	}{
+		{
+			want: "[--port=int]                   port to listen on [default: 8080] [env: PORT]\n",
+		},
+		{
+			opts: []FlaghandlerOptArg{UsageShowDefaults(false), UsageShowConfig(true)},
+			want: "[--port=int]                   port to listen on [env: PORT] [config: listen.port]\n",
+		},
+		{
+			opts: []FlaghandlerOptArg{UsageShowEnv(false)},
+			want: "[--port=int]                   port to listen on [default: 8080]\n",
+		},
+	}
+	for _, tc := range cases {
+		fh := PosixFlagHandler(append([]FlaghandlerOptArg{WithArgs(nil), UsageWidth(-1)}, tc.opts...)...)
+		registry := NewRegistry(WithFiller("flag", fh))
+		var got options
+		require.NoError(t, registry.Request(&got))
+		require.NoError(t, registry.Configure())
+		assert.Equal(t, 8080, got.Port)
+		usage := fh.Usage()
+		assert.Contains(t, usage, tc.want)
+		assert.Contains(t, usage, "[--name=Name]                  set Name (string)\n")
+	}
+}
+
+func TestUsageAnnotationsOtherTags(t *testing.T) {
+	type options struct {
+		Port int `flag:"port" env:"PORT" environ:"APP_PORT" file:"listen.port" help:"port to listen on"`
+	}
+	fh := PosixFlagHandler(WithArgs(nil), UsageWidth(-1), UsageShowConfig(true))
+	registry := NewRegistry(
+		WithoutFillers(),
+		WithFiller("environ", NewEnvFiller()),
+		WithFiller("file", NewFileFiller()),
+		WithFiller("flag", fh))
+	var got options
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	assert.Contains(t, fh.Usage(), "[--port=int]                   port to listen on [env: APP_PORT] [config: listen.port]\n")
+}
+
+func TestWrapLine(t *testing.T) {
+	cases := []struct {
+		line   string
+		indent int
+		width  int
+		want   string
+	}{
+		{line: "aaa bbb ccc", indent: 2, width: 0, want: "aaa bbb ccc"},
+		{line: "aaa bbb ccc", indent: 2, width: 7, want: "aaa bbb\n  ccc"},
+		{line: "aaa bbb ccc", indent: 2, width: 5, want: "aaa\n  bbb\n  ccc"},
+		{line: "aaaaaaaa bb", indent: 2, width: 5, want: "aaaaaaaa\n  bb"},
+		{line: "aaaaaaaa", indent: 2, width: 5, want: "aaaaaaaa"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, wrapLine(tc.line, tc.indent, tc.width), tc.line)
+	}
+}
@@ -0,0 +1,117 @@
+package nfigure
+
+import (
+	"sync"
+
+	"github.com/muir/commonerrors"
+	"github.com/pkg/errors"
+)
+
+var defaultRegistry = struct {
+	lock     sync.Mutex
+	registry *Registry
+}{
+	registry: NewRegistry(),
+}
+
+// Default returns the process-global default Registry.  It is used by
+// Register, ConfigFile, and Configure.  It starts as a Registry created
+// with NewRegistry() and can be replaced with Registry.AdoptDefault().
+func Default() *Registry {
+	defaultRegistry.lock.Lock()
+	defer defaultRegistry.lock.Unlock()
+	return defaultRegistry.registry
+}
+
+// Register is Registry.Request for the default Registry.  It is not
+// called Request because that name is taken by the Request type.
+// Libraries can call it from init():
+//
+//	func init() {
+//		_ = nfigure.Register(&config)
+//	}
+func Register(model interface{}, options ...RegistryFuncArg) error {
+	return Default().Request(model, options...)
+}
+
+// ConfigFile is Registry.ConfigFile for the default Registry.
+func ConfigFile(path string, prefix ...string) error {
+	return Default().ConfigFile(path, prefix...)
+}
+
+// Configure is Registry.Configure for the default Registry.
+func Configure() error {
+	return Default().Configure()
+}
+
+// AdoptDefault moves the requests and config files of the default
+// Registry into r and then makes r the default Registry so that
+// requests made later with Register are not lost either.
+//
+// It is an error to adopt after Configure has been called on the
+// default Registry.  If adopting fails, both registries are left as
+// they were.
+func (r *Registry) AdoptDefault() error {
+	defaultRegistry.lock.Lock()
+	defer defaultRegistry.lock.Unlock()
+	old := defaultRegistry.registry
+	if old == r {
+		return nil
+	}
+	old.lock.Lock()
+	defer old.lock.Unlock()
+	if old.configureStarted {
+		return commonerrors.ProgrammerError(errors.New("cannot adopt the default registry after it has been configured"))
+	}
+	restore := r.snapshot()
+	err := r.adopt(old.requests, old.configFiles)
+	if err != nil {
+		restore()
+		for _, req := range old.requests {
+			req.registry = old
+		}
+		return err
+	}
+	old.requests = nil
+	old.configFiles = nil
+	defaultRegistry.registry = r
+	return nil
+}
+
+func (r *Registry) adopt(requests []*Request, configFiles []configFile) error {
+	for _, cf := range configFiles {
+		var err error
+		if cf.source != nil {
+			err = r.configSource(cf.source, cf.prefix)
+		} else {
+			err = r.ConfigFile(cf.path, cf.prefix...)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, req := range requests {
+		err := r.addRequest(req)
+		if err != nil {
+			return errors.Wrap(err, req.name)
+		}
+	}
+	return nil
+}
+
+// snapshot returns a func that puts the fillers, config files, and
+// requests of r back to what they are now
+func (r *Registry) snapshot() func() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	fillers := r.fillers.Copy()
+	configFiles := r.configFiles
+	requests := r.requests
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.fillers = fillers
+		r.configFiles = configFiles
+		r.requests = requests
+	}
+}
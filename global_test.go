@@ -0,0 +1,80 @@
+package nfigure
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/muir/commonerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetDefault(t *testing.T) {
+	saved := Default()
+	defaultRegistry.registry = NewRegistry()
+	t.Cleanup(func() { defaultRegistry.registry = saved })
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	resetDefault(t)
+	var got struct {
+		II int
+	}
+	require.NoError(t, ConfigFile("source.yaml"))
+	require.NoError(t, Register(&got))
+	require.NoError(t, Configure())
+	assert.Equal(t, 10, got.II)
+}
+
+func TestAdoptDefault(t *testing.T) {
+	resetDefault(t)
+	var early struct {
+		II int
+	}
+	var late struct {
+		JJ int `config:"jj"`
+	}
+	require.NoError(t, ConfigFile("source.yaml"))
+	require.NoError(t, Register(&early))
+
+	registry := NewRegistry()
+	require.NoError(t, registry.AdoptDefault())
+	assert.Same(t, registry, Default())
+	require.NoError(t, Register(&late))
+	require.Len(t, registry.GetRequests(), 2)
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, 10, early.II)
+	assert.Equal(t, 12, late.JJ)
+}
+
+func TestAdoptDefaultAfterConfigure(t *testing.T) {
+	resetDefault(t)
+	require.NoError(t, Configure())
+	err := NewRegistry().AdoptDefault()
+	if assert.Error(t, err) {
+		assert.True(t, commonerrors.IsProgrammerError(err), "programmer error")
+	}
+}
+
+func TestAdoptDefaultFailure(t *testing.T) {
+	resetDefault(t)
+	var got struct {
+		II int
+	}
+	require.NoError(t, ConfigFile("source.yaml"))
+	require.NoError(t, Register(&got))
+	old := Default()
+
+	registry := NewRegistry(WithFiller("config", NewFileFiller(WithFS(fstest.MapFS{}))))
+	err := registry.AdoptDefault()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "source.yaml")
+	assert.Same(t, old, Default())
+	assert.Empty(t, registry.GetRequests())
+	require.Len(t, old.GetRequests(), 1)
+	assert.Same(t, old, old.GetRequests()[0].Registry())
+
+	require.NoError(t, Configure())
+	assert.Equal(t, 10, got.II)
+}
@@ -0,0 +1,96 @@
+package nfigure
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/muir/commonerrors"
+	"github.com/pkg/errors"
+)
+
+// Defaulter can be implemented by configuration structs, at any depth,
+// to set defaults in code.  SetDefaults is called before the struct's
+// fields are filled so anything that is filled overrides it.
+type Defaulter interface {
+	SetDefaults()
+}
+
+// Validator can be implemented by configuration structs, at any depth,
+// for checks that involve more than one field.  Validate is called after
+// all filling is done.  Inner structs are validated before the structs
+// that contain them.  Errors are wrapped with the path to the struct
+// (like "DB.Replicas[0]") and with commonerrors.ValidationError.
+type Validator interface {
+	Validate() error
+}
+
+// setDefaults calls SetDefaults if v implements Defaulter.
+func setDefaults(v reflect.Value) {
+	if d, ok := hookTarget(v).(Defaulter); ok {
+		debug("fill: SetDefaults", v.Type())
+		d.SetDefaults()
+	}
+}
+
+// hookTarget returns v as an interface, by pointer if possible, so that
+// methods with pointer receivers can be found.
+func hookTarget(v reflect.Value) interface{} {
+	if v.CanAddr() && v.Addr().CanInterface() {
+		return v.Addr().Interface()
+	}
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	return nil
+}
+
+// validateHooks walks a filled model and calls Validate on everything
+// that implements Validator.
+func validateHooks(path string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateHooks(path, v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			err := validateHooks(joinPath(path, f.Name), v.Field(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			err := validateHooks(indexPath(path, strconv.Itoa(i)), v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			err := validateHooks(indexPath(path, keyString(iter.Key())), iter.Value())
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+	if validator, ok := hookTarget(v).(Validator); ok {
+		err := validator.Validate()
+		if err != nil {
+			if path == "" {
+				path = v.Type().String()
+			}
+			return commonerrors.ValidationError(errors.Wrap(err, path))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package nfigure
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/muir/commonerrors"
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hookPool struct {
+	Size    int `env:"POOL_SIZE"`
+	MaxIdle int
+}
+
+func (p *hookPool) SetDefaults() {
+	p.Size = 4
+	p.MaxIdle = 2
+}
+
+func (p hookPool) Validate() error {
+	if p.MaxIdle > p.Size {
+		return errors.Errorf("MaxIdle (%d) is more than Size (%d)", p.MaxIdle, p.Size)
+	}
+	return nil
+}
+
+type hookConfig struct {
+	Name     string `env:"HOOK_NAME"`
+	Pool     hookPool
+	Replicas []hookPool `config:"replicas"`
+	Optional *hookPool
+}
+
+func (c *hookConfig) SetDefaults() {
+	c.Name = "default-name"
+}
+
+func (c *hookConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("Name is required")
+	}
+	return nil
+}
+
+func TestDefaulterAndValidator(t *testing.T) {
+	cases := []struct {
+		name  string
+		env   map[string]string
+		want  hookConfig
+		error string
+	}{
+		{
+			name: "defaults",
+			want: hookConfig{
+				Name: "default-name",
+				Pool: hookPool{Size: 4, MaxIdle: 2},
+			},
+		},
+		{
+			name: "filled overrides defaults",
+			env:  map[string]string{"HOOK_NAME": "n", "POOL_SIZE": "10"},
+			want: hookConfig{
+				Name:     "n",
+				Pool:     hookPool{Size: 10, MaxIdle: 2},
+				Optional: &hookPool{Size: 10, MaxIdle: 2},
+			},
+		},
+		{
+			name:  "nested validation",
+			env:   map[string]string{"POOL_SIZE": "1"},
+			error: "Pool: MaxIdle (2) is more than Size (1)",
+		},
+		{
+			name:  "top-level validation",
+			env:   map[string]string{"HOOK_NAME": ""},
+			error: "nfigure.hookConfig: Name is required",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			registry := NewRegistry()
+			var got hookConfig
+			require.NoError(t, registry.Request(&got))
+			err := registry.Configure()
+			if tc.error != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.error)
+					assert.True(t, commonerrors.IsValidationError(err), "validation error")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidatorElements(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.ConfigMap(map[string]any{
+		"replicas": []map[string]any{
+			{"Size": 3, "MaxIdle": 1},
+			{"Size": 1, "MaxIdle": 5},
+		},
+	}))
+	var got hookConfig
+	require.NoError(t, registry.Request(&got))
+	err := registry.Configure()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Replicas[1]: MaxIdle (5) is more than Size (1)")
+		assert.True(t, commonerrors.IsValidationError(err), "validation error")
+	}
+}
+
+// hookTextPool is filled as a whole from JSON text
+type hookTextPool hookPool
+
+func (p *hookTextPool) SetDefaults() {
+	p.Size = 4
+	p.MaxIdle = 2
+}
+
+func (p *hookTextPool) UnmarshalText(b []byte) error {
+	return json.Unmarshal(b, (*hookPool)(p))
+}
+
+func TestDefaulterFilledAsWhole(t *testing.T) {
+	t.Setenv("HOOK_POOL", `{"Size": 8, "MaxIdle": 3}`)
+	t.Setenv("HOOK_TEXT_POOL", `{"Size": 6}`)
+	registry := NewRegistry()
+	var got struct {
+		Pool     *hookPool    `env:"HOOK_POOL,JSON"`
+		TextPool hookTextPool `env:"HOOK_TEXT_POOL"`
+	}
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, &hookPool{Size: 8, MaxIdle: 3}, got.Pool)
+	assert.Equal(t, hookTextPool{Size: 6, MaxIdle: 2}, got.TextPool)
+}
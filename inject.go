@@ -0,0 +1,68 @@
+package nfigure
+
+import (
+	"fmt"
+
+	"github.com/muir/nject/v2"
+)
+
+// NewRegistryFactory returns an nject provider of *Registry for use with
+// [github.com/muir/nject] and nserve.  The Registry is created once with
+// NewRegistry(options...) and adopts the default registry so that requests
+// made with Register and Provide are filled by it.
+func NewRegistryFactory(options ...RegistryFuncArg) nject.Provider {
+	return nject.Provide("nfigure-registry", nject.Singleton(func() (*Registry, nject.TerminalError) {
+		r := NewRegistry(options...)
+		err := r.AdoptDefault()
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	}))
+}
+
+// Provide returns an nject provider of T, a struct that is filled in
+// by a Registry.  The request for T is made with Register when Provide is
+// called so that it is known before configuration starts. The keys are
+// given to FromRoot.
+//
+// The provider consumes a *Registry (from NewRegistryFactory) and calls
+// Configure on it the first time any Provide-generated provider runs.
+// Do not also call Configure on that Registry.  Configuration errors
+// stop the injection chain.
+//
+//	type ServerConfig struct {
+//		Port int `flag:"port" default:"8080"`
+//	}
+//
+//	nserve.CreateApp("myApp",
+//		nfigure.NewRegistryFactory(WithFiller("flag", PosixFlagHandler())),
+//		nfigure.Provide[ServerConfig](),
+//		func(config ServerConfig) { ... })
+func Provide[T any](keys ...string) nject.Provider {
+	model := new(T)
+	var options []RegistryFuncArg
+	if len(keys) != 0 {
+		options = append(options, FromRoot(keys...))
+	}
+	err := Register(model, options...)
+	return nject.Provide(fmt.Sprintf("nfigure-%T", *model), func(r *Registry) (T, nject.TerminalError) {
+		if err != nil {
+			return *model, err
+		}
+		err := r.AdoptDefault()
+		if err != nil {
+			return *model, err
+		}
+		return *model, r.configureOnce()
+	})
+}
+
+// configureOnce calls Configure the first time it is invoked and returns
+// the same result every time after that.
+func (r *Registry) configureOnce() error {
+	r.once.Do(func() {
+		r.onceError = r.Configure()
+	})
+	return r.onceError
+}
@@ -0,0 +1,57 @@
+package nfigure
+
+import (
+	"testing"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nject/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type injectServer struct {
+	Port int    `flag:"port" default:"8080"`
+	Name string `flag:"name"`
+}
+
+type injectLibrary struct {
+	OO string
+}
+
+func TestProvide(t *testing.T) {
+	resetDefault(t)
+	fh := PosixFlagHandler(WithArgs([]string{"--name", "n"}))
+	var server injectServer
+	var library injectLibrary
+	var registry *Registry
+	require.NoError(t, ConfigFile("source.yaml"))
+	err := nject.Run("test",
+		NewRegistryFactory(WithFiller("flag", fh)),
+		Provide[injectServer](),
+		Provide[injectLibrary]("MM"),
+		func(s injectServer, l injectLibrary, r *Registry) {
+			server = s
+			library = l
+			registry = r
+		})
+	require.NoError(t, err)
+	assert.Equal(t, injectServer{Port: 8080, Name: "n"}, server)
+	assert.Equal(t, "source.yaml", library.OO)
+	assert.Same(t, registry, Default())
+	assert.Len(t, registry.GetRequests(), 2)
+}
+
+func TestProvideError(t *testing.T) {
+	resetDefault(t)
+	fh := PosixFlagHandler(WithArgs([]string{"--bogus"}))
+	err := nject.Run("test",
+		NewRegistryFactory(WithFiller("flag", fh)),
+		Provide[injectServer](),
+		func(s injectServer) {
+			t.Error("should not be called")
+		})
+	if assert.Error(t, err) {
+		assert.True(t, commonerrors.IsUsageError(err), "usage error")
+	}
+}
@@ -0,0 +1,235 @@
+package nfigure
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/muir/nflex"
+	"github.com/pkg/errors"
+)
+
+// KeyMatching is the policy for matching field names and config tag
+// names to the keys in configuration files.
+type KeyMatching int
+
+const (
+	// MatchExact requires keys to be exactly the field name or config tag.
+	// This is the default.
+	MatchExact KeyMatching = iota
+	// MatchCaseInsensitive ignores case: "dbport" matches DBPort.
+	MatchCaseInsensitive
+	// MatchSnakeCase compares names as snake_case: "db_port" matches DBPort.
+	MatchSnakeCase
+	// MatchKebabCase compares names as kebab-case: "db-port" matches DBPort.
+	MatchKebabCase
+	// MatchCamelCase compares names as camelCase: "dbPort" matches DBPort.
+	MatchCamelCase
+)
+
+// WithKeyMatching sets how keys in configuration files are matched.  An
+// exact match is always used if there is one.  Otherwise, both the name
+// being looked up and the keys in the file are normalized and compared.
+// If more than one key in a mapping normalizes to the name, filling fails
+// with a configuration error.
+//
+//	type Config struct {
+//		DBPort int // matches "db_port" with MatchSnakeCase
+//	}
+func WithKeyMatching(matching KeyMatching) FileFillerOpts {
+	return func(s *FileFiller) {
+		s.matching = matching
+	}
+}
+
+func (m KeyMatching) normalize(name string) string {
+	switch m {
+	case MatchCaseInsensitive:
+		return strings.ToLower(name)
+	case MatchSnakeCase:
+		return strings.Join(splitWords(name), "_")
+	case MatchKebabCase:
+		return strings.Join(splitWords(name), "-")
+	case MatchCamelCase:
+		words := splitWords(name)
+		for i := 1; i < len(words); i++ {
+			r, size := utf8.DecodeRuneInString(words[i])
+			words[i] = string(unicode.ToUpper(r)) + words[i][size:]
+		}
+		return strings.Join(words, "")
+	default:
+		return name
+	}
+}
+
+// splitWords breaks a name into lower-case words at separators ("_", "-",
+// ".", and space) and at changes of case: "DBPort" is "db", "port".
+func splitWords(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	flush := func() {
+		if len(word) != 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || unicode.IsSpace(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// matchingSource wraps an nflex.Source so that keys are looked up
+// according to a KeyMatching policy
+type matchingSource struct {
+	source   nflex.Source
+	matching KeyMatching
+}
+
+var _ nflex.Source = matchingSource{}
+
+func newMatchingSource(source nflex.Source, matching KeyMatching) nflex.Source {
+	if matching == MatchExact {
+		return source
+	}
+	return matchingSource{source: source, matching: matching}
+}
+
+// resolve finds the key in the mapping that matches name
+func (m matchingSource) resolve(name string) (string, error) {
+	if m.source.Exists(name) {
+		return name, nil
+	}
+	keys, err := m.source.Keys()
+	if err != nil {
+		return name, nil
+	}
+	want := m.matching.normalize(name)
+	var matches []string
+	for _, key := range keys {
+		if m.matching.normalize(key) == want {
+			matches = append(matches, key)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return name, nil
+	case 1:
+		return matches[0], nil
+	default:
+		return "", errors.Errorf("keys %s are ambiguous for %s", strings.Join(matches, ", "), name)
+	}
+}
+
+func (m matchingSource) resolvePath(keys []string) ([]string, error) {
+	resolved := make([]string, len(keys))
+	current := nflex.Source(m)
+	for i, key := range keys {
+		if current == nil {
+			resolved[i] = key
+			continue
+		}
+		if ms, ok := current.(matchingSource); ok {
+			var err error
+			key, err = ms.resolve(key)
+			if err != nil {
+				return nil, err
+			}
+			current = ms.source.Recurse(key)
+			if current != nil {
+				current = matchingSource{source: current, matching: m.matching}
+			}
+		}
+		resolved[i] = key
+	}
+	return resolved, nil
+}
+
+func (m matchingSource) Exists(keys ...string) bool {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return false
+	}
+	return m.source.Exists(resolved...)
+}
+
+func (m matchingSource) GetBool(keys ...string) (bool, error) {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return false, err
+	}
+	return m.source.GetBool(resolved...)
+}
+
+func (m matchingSource) GetInt(keys ...string) (int64, error) {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return 0, err
+	}
+	return m.source.GetInt(resolved...)
+}
+
+func (m matchingSource) GetFloat(keys ...string) (float64, error) {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return 0, err
+	}
+	return m.source.GetFloat(resolved...)
+}
+
+func (m matchingSource) GetString(keys ...string) (string, error) {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return "", err
+	}
+	return m.source.GetString(resolved...)
+}
+
+func (m matchingSource) Recurse(keys ...string) nflex.Source {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return nil
+	}
+	source := m.source.Recurse(resolved...)
+	if source == nil {
+		return nil
+	}
+	return matchingSource{source: source, matching: m.matching}
+}
+
+func (m matchingSource) Keys(keys ...string) ([]string, error) {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return nil, err
+	}
+	return m.source.Keys(resolved...)
+}
+
+func (m matchingSource) Len(keys ...string) (int, error) {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return 0, err
+	}
+	return m.source.Len(resolved...)
+}
+
+func (m matchingSource) Type(keys ...string) nflex.NodeType {
+	resolved, err := m.resolvePath(keys)
+	if err != nil {
+		return nflex.Undefined
+	}
+	return m.source.Type(resolved...)
+}
@@ -0,0 +1,144 @@
+package nfigure
+
+import (
+	"testing"
+
+	"github.com/muir/commonerrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWords(t *testing.T) {
+	cases := map[string][]string{
+		"DBPort":        {"db", "port"},
+		"dbPort":        {"db", "port"},
+		"db_port":       {"db", "port"},
+		"db-port":       {"db", "port"},
+		"HTTPServer2Go": {"http", "server2", "go"},
+		"port":          {"port"},
+		"":              nil,
+	}
+	for name, want := range cases {
+		assert.Equal(t, want, splitWords(name), name)
+	}
+	assert.Equal(t, "dbPort", MatchCamelCase.normalize("DB_PORT"))
+	assert.Equal(t, "userÑame", MatchCamelCase.normalize("user_ñame"))
+	assert.NotEqual(t, MatchCamelCase.normalize("user_ñame"), MatchCamelCase.normalize("user_ıame"))
+	assert.Equal(t, "db-port", MatchKebabCase.normalize("DBPort"))
+	assert.Equal(t, "db_port", MatchSnakeCase.normalize("dbPort"))
+	assert.Equal(t, "dbport", MatchCaseInsensitive.normalize("DBPort"))
+	assert.Equal(t, "DBPort", MatchExact.normalize("DBPort"))
+}
+
+type keyMatchDatabase struct {
+	HostName string
+	Port     int `config:"port"`
+}
+
+type keyMatchConfig struct {
+	MaxConns     int
+	Database     keyMatchDatabase
+	ExtraHeaders map[string]string
+}
+
+func TestKeyMatching(t *testing.T) {
+	cases := []struct {
+		name     string
+		file     string
+		matching KeyMatching
+		want     keyMatchConfig
+		error    string
+	}{
+		{
+			name:     "exact does not match snake",
+			file:     "keymatch-snake.yaml",
+			matching: MatchExact,
+		},
+		{
+			name:     "snake",
+			file:     "keymatch-snake.yaml",
+			matching: MatchSnakeCase,
+			want: keyMatchConfig{
+				MaxConns:     5,
+				Database:     keyMatchDatabase{HostName: "db", Port: 3306},
+				ExtraHeaders: map[string]string{"X_Trace": "on"},
+			},
+		},
+		{
+			name:     "kebab",
+			file:     "keymatch-kebab.yaml",
+			matching: MatchKebabCase,
+			want: keyMatchConfig{
+				MaxConns: 6,
+				Database: keyMatchDatabase{HostName: "kebab"},
+			},
+		},
+		{
+			name:     "case insensitive",
+			file:     "keymatch-kebab.yaml",
+			matching: MatchCaseInsensitive,
+		},
+		{
+			name:     "exact match preferred",
+			file:     "keymatch-mixed.yaml",
+			matching: MatchCamelCase,
+			want:     keyMatchConfig{MaxConns: 7},
+		},
+		{
+			name:     "ambiguous",
+			file:     "keymatch-ambiguous.yaml",
+			matching: MatchSnakeCase,
+			error:    "keymatch-ambiguous.yaml:1:1: keys max_conns, maxConns are ambiguous for MaxConns",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := NewRegistry(WithFiller("config", NewFileFiller(WithKeyMatching(tc.matching))))
+			require.NoError(t, registry.ConfigFile(tc.file))
+			var got keyMatchConfig
+			require.NoError(t, registry.Request(&got))
+			err := registry.Configure()
+			if tc.error != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.error)
+					assert.True(t, commonerrors.IsConfigurationError(err), "configuration error")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestKeyMatchingErrorOrigin(t *testing.T) {
+	registry := NewRegistry(WithFiller("config", NewFileFiller(WithKeyMatching(MatchSnakeCase))))
+	require.NoError(t, registry.ConfigFile("keymatch-badport.yaml"))
+	var got struct {
+		Database struct {
+			Port int
+		}
+	}
+	require.NoError(t, registry.Request(&got))
+	err := registry.Configure()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "keymatch-badport.yaml:2:9: database.port: ")
+	}
+}
+
+func TestKeyMatchingWithPrefix(t *testing.T) {
+	registry := NewRegistry(WithFiller("config", NewFileFiller(WithKeyMatching(MatchSnakeCase))))
+	require.NoError(t, registry.ConfigFile("keymatch-ambiguous.yaml", "app"))
+	var got struct {
+		App struct {
+			MaxConns int
+		} `config:"app"`
+	}
+	require.NoError(t, registry.Request(&got))
+	err := registry.Configure()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "keymatch-ambiguous.yaml:1:1: keys max_conns, maxConns are ambiguous for MaxConns")
+		assert.NotContains(t, err.Error(), "app:")
+	}
+}
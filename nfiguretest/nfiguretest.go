@@ -0,0 +1,198 @@
+// Package nfiguretest builds nfigure Registries for tests.
+//
+// Registries built by NewRegistry do not look at os.Args, the process
+// environment, the real clock, or the filesystem, so tests that use them
+// can call t.Parallel().
+//
+//	func TestServer(t *testing.T) {
+//		t.Parallel()
+//		var config ServerConfig
+//		registry := nfiguretest.NewRegistry(t,
+//			nfiguretest.Env(map[string]string{"PORT": "80"}),
+//			nfiguretest.YAML("name: x"),
+//			nfiguretest.Args("--debug"))
+//		require.NoError(t, registry.Request(&config))
+//		require.NoError(t, registry.Configure())
+//		nfiguretest.AssertFilledBy(t, registry, &config, "Port", "env")
+//	}
+package nfiguretest
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nfigure"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DefaultTime is what Registry.Now() returns unless Clock is used.
+var DefaultTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Option configures NewRegistry
+type Option func(*config)
+
+type config struct {
+	env          map[string]string
+	docs         []document
+	args         []string
+	flagOpts     []nfigure.FlaghandlerOptArg
+	registryOpts []nfigure.RegistryFuncArg
+	now          time.Time
+}
+
+type document struct {
+	ext  string
+	data []byte
+	m    map[string]any
+}
+
+// Env provides the environment variables for the "env" filler.  Multiple
+// calls add to the environment.
+func Env(env map[string]string) Option {
+	return func(c *config) {
+		for k, v := range env {
+			c.env[k] = v
+		}
+	}
+}
+
+// YAML adds an inline configuration file.  Files are added in order.
+func YAML(doc string) Option {
+	return func(c *config) {
+		c.docs = append(c.docs, document{ext: "yaml", data: []byte(doc)})
+	}
+}
+
+// JSON adds an inline configuration file.  Files are added in order.
+func JSON(doc string) Option {
+	return func(c *config) {
+		c.docs = append(c.docs, document{ext: "json", data: []byte(doc)})
+	}
+}
+
+// Map adds configuration from a Go map with Registry.ConfigMap.
+// Documents and maps are added in order.
+func Map(m map[string]any) Option {
+	return func(c *config) {
+		c.docs = append(c.docs, document{m: m})
+	}
+}
+
+// Args provides the command line (without the program name) for the
+// "flag" filler.  Without Args, the command line is empty.
+func Args(args ...string) Option {
+	return func(c *config) {
+		c.args = args
+	}
+}
+
+// Flags adds options for the PosixFlagHandler that is the "flag" filler.
+func Flags(opts ...nfigure.FlaghandlerOptArg) Option {
+	return func(c *config) {
+		c.flagOpts = append(c.flagOpts, opts...)
+	}
+}
+
+// RegistryOptions adds options for nfigure.NewRegistry.  They are
+// applied after the fillers that NewRegistry sets up so they can
+// replace them.
+func RegistryOptions(opts ...nfigure.RegistryFuncArg) Option {
+	return func(c *config) {
+		c.registryOpts = append(c.registryOpts, opts...)
+	}
+}
+
+// Clock sets the frozen time returned by Registry.Now().
+func Clock(now time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// NewRegistry creates a Registry with the following fillers:
+//
+//	env		fill from the map given with Env
+//	config		fill from the documents given with YAML, JSON, and Map
+//	default		fill from the tag value
+//	flag		fill from the Args with a PosixFlagHandler
+func NewRegistry(t testing.TB, options ...Option) *nfigure.Registry {
+	t.Helper()
+	c := config{
+		env: make(map[string]string),
+		now: DefaultTime,
+	}
+	for _, f := range options {
+		f(&c)
+	}
+	files := make(fstest.MapFS)
+	names := make([]string, len(c.docs))
+	for i, doc := range c.docs {
+		if doc.m == nil {
+			names[i] = fmt.Sprintf("inline%d.%s", i, doc.ext)
+			files[names[i]] = &fstest.MapFile{Data: doc.data}
+		}
+	}
+	env := c.env
+	now := c.now
+	registry := nfigure.NewRegistry(append([]nfigure.RegistryFuncArg{
+		nfigure.WithFiller("env", nfigure.NewLookupFillerSimple(func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+			nfigure.WrapLookupErrors(commonerrors.EnvironmentError),
+			nfigure.DescribeLookups(func(key string) string { return "$" + key }))),
+		nfigure.WithFiller("config", nfigure.NewFileFiller(nfigure.WithFS(files))),
+		nfigure.WithFiller("flag", nfigure.PosixFlagHandler(
+			append([]nfigure.FlaghandlerOptArg{nfigure.WithArgs(c.args)}, c.flagOpts...)...)),
+		nfigure.WithClock(func() time.Time { return now }),
+	}, c.registryOpts...)...)
+	for i, doc := range c.docs {
+		if doc.m != nil {
+			require.NoError(t, registry.ConfigMap(doc.m), "inline configuration %d", i)
+			continue
+		}
+		require.NoError(t, registry.ConfigFile(names[i]), "inline configuration %s", names[i])
+	}
+	return registry
+}
+
+// AssertFilledBy checks that the field at path in model (a pointer that
+// was passed to Registry.Request) was filled by the filler with tag.
+// The path is a dot-separated list of Go field names.
+func AssertFilledBy(t testing.TB, registry *nfigure.Registry, model any, path string, tag string) bool {
+	t.Helper()
+	for _, request := range registry.GetRequests() {
+		if request.GetObject() != model {
+			continue
+		}
+		got, ok := request.FilledBy(path)
+		if !assert.True(t, ok, "%s was not filled", path) {
+			return false
+		}
+		return assert.Equal(t, tag, got, "filler for %s", path)
+	}
+	return assert.Fail(t, "model was not requested", "%T", model)
+}
+
+// AssertError checks that err is not nil, that kind(err) is true, and
+// that the error message contains each of the strings in contains.  kind
+// is usually one of the commonerrors predicates like commonerrors.IsUsageError.
+func AssertError(t testing.TB, err error, kind func(error) bool, contains ...string) bool {
+	t.Helper()
+	if !assert.Error(t, err) {
+		return false
+	}
+	ok := true
+	if kind != nil {
+		ok = assert.True(t, kind(err), "kind of error: %s", err) && ok
+	}
+	for _, want := range contains {
+		ok = assert.Contains(t, err.Error(), want) && ok
+	}
+	return ok
+}
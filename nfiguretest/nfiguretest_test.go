@@ -0,0 +1,68 @@
+package nfiguretest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nfigure/nfiguretest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Host    string   `env:"HOST" flag:"host"`
+	Port    int      `env:"PORT"`
+	Name    string   `config:"name"`
+	Tags    []string `config:"tags"`
+	Level   string   `default:"info"`
+	Verbose bool     `flag:"v"`
+}
+
+func TestNewRegistry(t *testing.T) {
+	t.Parallel()
+	var got testConfig
+	registry := nfiguretest.NewRegistry(t,
+		nfiguretest.Env(map[string]string{"HOST": "envhost", "PORT": "80"}),
+		nfiguretest.YAML("name: yaml\ntags: [a]"),
+		nfiguretest.Map(map[string]any{"tags": []string{"b", "c"}}),
+		nfiguretest.Args("-v"))
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, testConfig{
+		Host:    "envhost",
+		Port:    80,
+		Name:    "yaml",
+		Tags:    []string{"a", "b", "c"},
+		Level:   "info",
+		Verbose: true,
+	}, got)
+	nfiguretest.AssertFilledBy(t, registry, &got, "Host", "env")
+	nfiguretest.AssertFilledBy(t, registry, &got, "Name", "config")
+	nfiguretest.AssertFilledBy(t, registry, &got, "Level", "default")
+	nfiguretest.AssertFilledBy(t, registry, &got, "Verbose", "flag")
+	assert.Equal(t, nfiguretest.DefaultTime, registry.Now())
+}
+
+func TestJSONAndClock(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+	var got testConfig
+	registry := nfiguretest.NewRegistry(t,
+		nfiguretest.JSON(`{"name": "json"}`),
+		nfiguretest.Clock(now))
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, "json", got.Name)
+	assert.Empty(t, got.Host)
+	assert.Equal(t, now, registry.Now())
+}
+
+func TestAssertError(t *testing.T) {
+	t.Parallel()
+	var got testConfig
+	registry := nfiguretest.NewRegistry(t, nfiguretest.Args("--bogus"))
+	require.NoError(t, registry.Request(&got))
+	nfiguretest.AssertError(t, registry.Configure(), commonerrors.IsUsageError, "--bogus")
+}
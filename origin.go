@@ -0,0 +1,180 @@
+package nfigure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// position is where a value is in a configuration file.  Lines and
+// columns start at 1.
+type position struct {
+	line   int
+	column int
+}
+
+// filePositions maps key paths (joined by positionKey) to where their
+// values are.
+type filePositions map[string]position
+
+func positionKey(keyPath []string) string {
+	return strings.Join(keyPath, "\x00")
+}
+
+// readPositions finds the positions of the values in a configuration file.
+// Positions are only an aid for error messages so problems reading or
+// parsing the file result in no positions rather than an error: the real
+// parse is done by nflex.
+func (s FileFiller) readPositions(path string) filePositions {
+	var data []byte
+	var err error
+	switch {
+	case s.fsys != nil:
+		data, err = fs.ReadFile(s.fsys, path)
+	case !s.hasOptions:
+		data, err = os.ReadFile(path)
+	default:
+		// the options from WithUnmarshalOpts may read from somewhere else
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	positions := make(filePositions)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var node yaml.Node
+		if yaml.Unmarshal(data, &node) != nil {
+			return nil
+		}
+		positions.addYAML(nil, &node)
+	case ".json":
+		if positions.addJSON(nil, data) != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return positions
+}
+
+func (p filePositions) addYAML(keyPath []string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) != 0 {
+			p.addYAML(keyPath, node.Content[0])
+		}
+		return
+	case yaml.AliasNode:
+		return
+	}
+	p[positionKey(keyPath)] = position{line: node.Line, column: node.Column}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			p.addYAML(appendKey(keyPath, node.Content[i].Value), node.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			p.addYAML(appendKey(keyPath, strconv.Itoa(i)), n)
+		}
+	}
+}
+
+func (p filePositions) addJSON(keyPath []string, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var walk func(keyPath []string) error
+	walk = func(keyPath []string) error {
+		p[positionKey(keyPath)] = positionOf(data, dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				err = walk(appendKey(keyPath, fmt.Sprint(key)))
+				if err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				err := walk(appendKey(keyPath, strconv.Itoa(i)))
+				if err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	return walk(keyPath)
+}
+
+// positionOf finds the line and column of the value that starts at or
+// after offset, skipping whitespace and separators
+func positionOf(data []byte, offset int64) position {
+	i := int(offset)
+	for i < len(data) && strings.IndexByte(" \t\r\n:,", data[i]) != -1 {
+		i++
+	}
+	line := 1 + bytes.Count(data[:i], []byte{'\n'})
+	column := i + 1
+	if nl := bytes.LastIndexByte(data[:i], '\n'); nl != -1 {
+		column = i - nl
+	}
+	return position{line: line, column: column}
+}
+
+func appendKey(keyPath []string, key string) []string {
+	n := make([]string, len(keyPath), len(keyPath)+1)
+	copy(n, keyPath)
+	return append(n, key)
+}
+
+// origin describes the file, position, and key path of the value for the
+// current key path, like "config.yaml:3:9: db.port".  The first file that
+// has the key is used, or the last one if firstFirst is false, the same
+// way that Fill picks between files.
+func (s FileFiller) origin(firstFirst bool) string {
+	for i := range s.origins {
+		origin := s.origins[i]
+		if !firstFirst {
+			origin = s.origins[len(s.origins)-1-i]
+		}
+		if origin.source.Exists() {
+			return origin.describe()
+		}
+	}
+	return ""
+}
+
+func (o fileOrigin) describe() string {
+	name := o.file()
+	if len(o.keyPath) == 0 {
+		return name
+	}
+	return name + ": " + strings.Join(o.keyPath, ".")
+}
+
+// file is the name of the file with the line and column, when known,
+// like "config.yaml:3:9"
+func (o fileOrigin) file() string {
+	if pos, ok := o.positions[positionKey(o.keyPath)]; ok {
+		return fmt.Sprintf("%s:%d:%d", o.name, pos.line, pos.column)
+	}
+	return o.name
+}
@@ -0,0 +1,99 @@
+package nfigure
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/nflex"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileFillerErrorOrigin(t *testing.T) {
+	files := fstest.MapFS{
+		"good.yaml": {Data: []byte("port: 80\n")},
+		"bad.yaml": {Data: []byte("name: x\n" +
+			"db:\n" +
+			"  port: eighty\n")},
+		"hosts.yaml": {Data: []byte("db:\n" +
+			"  hosts:\n" +
+			"    - a\n" +
+			"    - [b]\n")},
+		"bad.json": {Data: []byte("{\n" +
+			"  \"name\": \"x\",\n" +
+			"  \"db\": {\"port\": \"eighty\"}\n" +
+			"}\n")},
+		"timeout.yaml": {Data: []byte("db:\n" +
+			"  timeout: 5\n")},
+		"late.yaml": {Data: []byte("db:\n" +
+			"  timeout: soon\n")},
+	}
+	type db struct {
+		Port    int      `config:"port"`
+		Hosts   []string `config:"hosts"`
+		Timeout int      `config:"timeout" nfigure:",last"`
+	}
+	cases := []struct {
+		name  string
+		files []string
+		opts  []FileFillerOpts
+		error string
+	}{
+		{
+			name:  "yaml",
+			files: []string{"bad.yaml"},
+			opts:  []FileFillerOpts{WithFS(files)},
+			error: "bad.yaml:3:9: db.port: ",
+		},
+		{
+			name:  "yaml element",
+			files: []string{"hosts.yaml"},
+			opts:  []FileFillerOpts{WithFS(files)},
+			error: "hosts.yaml:4:7: db.hosts.1: ",
+		},
+		{
+			name:  "json",
+			files: []string{"good.yaml", "bad.json"},
+			opts:  []FileFillerOpts{WithFS(files)},
+			error: "bad.json:3:18: db.port: ",
+		},
+		{
+			name:  "last file wins",
+			files: []string{"timeout.yaml", "late.yaml"},
+			opts:  []FileFillerOpts{WithFS(files)},
+			error: "late.yaml:2:12: db.timeout: ",
+		},
+		{
+			name:  "WithFS then WithUnmarshalOpts",
+			files: []string{"bad.yaml"},
+			opts:  []FileFillerOpts{WithFS(files), WithUnmarshalOpts()},
+			error: "bad.yaml:3:9: db.port: ",
+		},
+		{
+			name:  "no positions without WithFS",
+			files: []string{"bad.yaml"},
+			opts:  []FileFillerOpts{WithUnmarshalOpts(nflex.WithFS(files))},
+			error: "bad.yaml: db.port: ",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := NewRegistry(WithFiller("config", NewFileFiller(tc.opts...)))
+			for _, file := range tc.files {
+				require.NoError(t, registry.ConfigFile(file))
+			}
+			var got struct {
+				Name string `config:"name"`
+				DB   db     `config:"db"`
+			}
+			require.NoError(t, registry.Request(&got))
+			err := registry.Configure()
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.error)
+				assert.True(t, commonerrors.IsConfigurationError(err), "configuration error")
+			}
+		})
+	}
+}
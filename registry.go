@@ -1,8 +1,11 @@
 package nfigure
 
 import (
+	"encoding/json"
 	"sync"
+	"time"
 
+	"github.com/muir/commonerrors"
 	"github.com/muir/nflex"
 	"github.com/pkg/errors"
 )
@@ -21,16 +24,28 @@ type Validate interface {
 // configuration (Requests).
 type Registry struct {
 	requests         []*Request
+	configFiles      []configFile
 	lock             sync.Mutex
 	configureStarted bool
+	once             sync.Once
+	onceError        error
 	registryConfig
 }
 
+// configFile records ConfigFile, ConfigMap, and ConfigValue so that they
+// can be repeated by AdoptDefault.  Only one of path and source is set.
+type configFile struct {
+	path   string
+	source nflex.Source
+	prefix []string
+}
+
 type registryConfig struct {
 	metaTag   string
 	validator Validate
 	fillers   *fillerCollection
 	prefix    []string
+	clock     func() time.Time
 }
 
 // RegistryFuncArg is used to set Registry options.
@@ -69,12 +84,29 @@ func WithoutFillers() RegistryFuncArg {
 // configuration structs after the configuration is complete.  Errors
 // reported by the validation function will be wrapped with
 // commonerrors.ValidationError and returned by Registry.Configgure()
+//
+// When a struct is not valid, its fields are checked one at a time with
+// StructPartial so that each error names where the field's value came from:
+// "$DB_PORT", "--db-port", or "config.yaml: database.port".  Errors for
+// fields that were filled from the command line are wrapped with
+// commonerrors.UsageError instead and come first.  When there are errors
+// of both kinds, the returned error is both a commonerrors.UsageError and
+// a commonerrors.ValidationError: errors.As finds each group.
+// Validation happens after filling, not while the command line is parsed.
 func WithValidate(v Validate) RegistryFuncArg {
 	return func(r *registryConfig) {
 		r.validator = v
 	}
 }
 
+// WithClock overrides where Registry.Now() gets the time.  It is meant
+// for tests that need a frozen clock.
+func WithClock(now func() time.Time) RegistryFuncArg {
+	return func(r *registryConfig) {
+		r.clock = now
+	}
+}
+
 // WithMetaTag specifies the name of the meta tag.
 //
 // The default meta tag is "nfigure".
@@ -138,8 +170,22 @@ func NewRegistry(options ...RegistryFuncArg) *Registry {
 	return r
 }
 
+// Now returns the current time or the time from the clock set with
+// WithClock.  Configuration code that depends on the time should use
+// it so that tests can control the time.
+func (r *Registry) Now() time.Time {
+	if r.clock != nil {
+		return r.clock()
+	}
+	return time.Now()
+}
+
 // ConfigFile adds a source of configuration to all Fillers that implement
 // CanAddConfigFileFiller will be be offered the config file.
+//
+// If prefix is given, the contents of the file are placed under that key
+// path: with ConfigFile("db.yaml", "database"), "host" in db.yaml fills
+// the field found at "database.host".
 func (r *Registry) ConfigFile(path string, prefix ...string) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -171,6 +217,7 @@ func (r *Registry) ConfigFile(path string, prefix ...string) error {
 		okay = true
 	}
 	if okay {
+		r.configFiles = append(r.configFiles, configFile{path: path, prefix: prefix})
 		return nil
 	}
 	if rejected != nil {
@@ -179,6 +226,55 @@ func (r *Registry) ConfigFile(path string, prefix ...string) error {
 	return errors.Errorf("Unable to read config from %s", path)
 }
 
+// ConfigMap adds data to all Fillers that implement CanAddConfigSourceFiller.
+// It is layered with configuration files in the order that ConfigFile,
+// ConfigMap, and ConfigValue are called.
+func (r *Registry) ConfigMap(m map[string]any, prefix ...string) error {
+	return r.ConfigValue(m, prefix...)
+}
+
+// ConfigValue is like ConfigMap for any Go value that can be encoded
+// as JSON.  Struct fields are named as encoding/json names them.
+func (r *Registry) ConfigValue(v any, prefix ...string) error {
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return commonerrors.ProgrammerError(errors.Wrap(err, "encode configuration value"))
+	}
+	source, err := nflex.UnmarshalJSON(enc)
+	if err != nil {
+		return commonerrors.ProgrammerError(errors.Wrap(err, "decode configuration value"))
+	}
+	return r.configSource(source, prefix)
+}
+
+func (r *Registry) configSource(source nflex.Source, prefix []string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var okay bool
+	for _, tag := range r.fillers.Order() {
+		filler := r.fillers.m[tag]
+		canAdd, ok := filler.(CanAddConfigSourceFiller)
+		if !ok {
+			debugf("filler %s does not support config sources", tag)
+			continue
+		}
+		n, err := canAdd.AddConfigSource(source, prefix)
+		if err != nil {
+			return errors.Wrap(err, tag)
+		}
+		okay = true
+		if n == nil {
+			continue
+		}
+		r.fillers.Add(tag, n)
+	}
+	if !okay {
+		return errors.New("No filler accepts configuration sources")
+	}
+	r.configFiles = append(r.configFiles, configFile{source: source, prefix: prefix})
+	return nil
+}
+
 /* TODO
 // Any type that implements ConfigureReactive that is filled in during
 // the configuration process will have React invoked upon it after filling
@@ -0,0 +1,68 @@
+package nfigure
+
+import (
+	"testing"
+
+	"github.com/muir/nflex"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigMapAndValue(t *testing.T) {
+	type computed struct {
+		Workers int               `json:"workers"`
+		Labels  map[string]string `json:"labels"`
+	}
+	var got struct {
+		II     int
+		ZZ     string `config:"zz"`
+		KK     []string
+		Server struct {
+			Workers int               `config:"workers"`
+			Labels  map[string]string `config:"labels"`
+		} `config:"computed"`
+	}
+	registry := NewRegistry(WithFiller("config", NewFileFiller(WithUnmarshalOpts(nflex.WithFS(content)))))
+	require.NoError(t, registry.ConfigFile("source.yaml"))
+	require.NoError(t, registry.ConfigMap(map[string]any{
+		"II": 99,
+		"zz": "from map",
+		"KK": []string{"z"},
+	}))
+	require.NoError(t, registry.ConfigValue(computed{
+		Workers: 8,
+		Labels:  map[string]string{"a": "b"},
+	}, "computed"))
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, 10, got.II, "file was first")
+	assert.Equal(t, "from map", got.ZZ)
+	assert.Equal(t, []string{"a", "b", "z"}, got.KK)
+	assert.Equal(t, 8, got.Server.Workers)
+	assert.Equal(t, map[string]string{"a": "b"}, got.Server.Labels)
+}
+
+func TestConfigValueUnsupported(t *testing.T) {
+	registry := NewRegistry()
+	assert.Error(t, registry.ConfigValue(make(chan int)))
+	registry = NewRegistry(WithoutFillers())
+	assert.Error(t, registry.ConfigMap(map[string]any{"a": 1}))
+}
+
+func TestConfigFilePrefix(t *testing.T) {
+	var got struct {
+		II  int
+		App struct {
+			II int
+			JJ int `config:"jj"`
+		} `config:"app"`
+	}
+	registry := NewRegistry()
+	require.NoError(t, registry.ConfigFile("source.yaml", "app"))
+	require.NoError(t, registry.Request(&got))
+	require.NoError(t, registry.Configure())
+	assert.Equal(t, 0, got.II)
+	assert.Equal(t, 10, got.App.II)
+	assert.Equal(t, 12, got.App.JJ)
+}
@@ -26,9 +26,12 @@ func FromRoot(keys ...string) RegistryFuncArg {
 
 // Request tracks a config struct that needs to be filled in.
 type Request struct {
-	registry *Registry
-	name     string
-	object   interface{}
+	registry   *Registry
+	name       string
+	object     interface{}
+	provenance map[string]string // Go path of filled fields -> filler tag
+	sources    map[string]string // Go path of filled fields -> where the value came from
+	configTag  string            // tag of the FileFiller, for contentFiller
 	registryConfig
 }
 
@@ -50,8 +53,13 @@ func (r *Registry) Request(model interface{}, options ...RegistryFuncArg) error
 	for _, f := range options {
 		f(&req.registryConfig)
 	}
+	return r.addRequest(req)
+}
+
+func (r *Registry) addRequest(req *Request) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
+	req.registry = r
 	r.requests = append(r.requests, req)
 	if r.configureStarted {
 		debug("request: prewalking since configuration has already started")
@@ -116,3 +124,12 @@ func (r *Request) getPrefix() []string {
 func (r *Request) GetObject() any {
 	return r.object
 }
+
+// FilledBy returns the tag of the filler that provided the value for
+// the field at path, a dot-separated list of Go field names with
+// [index] for array, slice, and map elements.  It is only valid after
+// Configure.
+func (r *Request) FilledBy(path string) (string, bool) {
+	tag, ok := r.provenance[path]
+	return tag, ok
+}
@@ -1,6 +1,7 @@
 package nfigure
 
 import (
+	"io/fs"
 	"reflect"
 	"strconv"
 
@@ -14,21 +15,54 @@ import (
 type FileFiller struct {
 	source          nflex.Source
 	umarshalOptions []nflex.UnmarshalFileArg
+	origins         []fileOrigin
+	fsys            fs.FS
+	matching        KeyMatching
+	deprecated      *deprecationReporter
+	hasOptions      bool // WithUnmarshalOpts was used
+}
+
+// fileOrigin tracks each file separately so that it is possible to say
+// which file provided a value
+type fileOrigin struct {
+	name      string
+	source    nflex.Source
+	positions filePositions
+	keyPath   []string // the keys as they are in the file
+	prefix    []string // keys from the ConfigFile prefix that are not in the file
+	offset    int      // for slices, the number of elements in earlier files
 }
 
 var _ CanRecurseFiller = FileFiller{}
 var _ CanLenFiller = FileFiller{}
 var _ CanKeysFiller = FileFiller{}
 var _ CanAddConfigFileFiller = FileFiller{}
+var _ CanAddConfigSourceFiller = FileFiller{}
+var _ sourceDescriber = FileFiller{}
 
 // FileFillerOpts is a functional arugment for NewFileFiller()
 type FileFillerOpts func(*FileFiller)
 
 // WithUnmarshalOpts passes through to
 // https://pkg.go.dev/github.com/muir/nflex#UnmarshalFile
+//
+// Since the options could read files from anywhere, error messages do
+// not include line numbers when WithUnmarshalOpts is used unless WithFS
+// is used too.
 func WithUnmarshalOpts(opts ...nflex.UnmarshalFileArg) FileFillerOpts {
 	return func(s *FileFiller) {
-		s.umarshalOptions = opts
+		s.umarshalOptions = append(s.umarshalOptions, opts...)
+		s.hasOptions = true
+	}
+}
+
+// WithFS reads configuration files from fsys instead of from the
+// operating system.  Unlike passing nflex.WithFS to WithUnmarshalOpts,
+// it allows error messages to include line numbers.
+func WithFS(fsys fs.FS) FileFillerOpts {
+	return func(s *FileFiller) {
+		s.fsys = fsys
+		s.umarshalOptions = append(s.umarshalOptions, nflex.WithFS(fsys))
 	}
 }
 
@@ -43,6 +77,17 @@ func WithUnmarshalOpts(opts ...nflex.UnmarshalFileArg) FileFillerOpts {
 //		MyField      string `config:"myField"` // fill this one
 //	}
 //
+// Allowed values can be restricted with "enum":
+//
+//	type MyStruct struct {
+//		Level string `config:"level,enum=debug|info|warn"`
+//	}
+//
+// Keys that have been renamed can be found by their old names with "alias":
+//
+//	type MyStruct struct {
+//		RequestTimeout time.Duration `config:"requestTimeout,alias=timeout|req_timeout"`
+//	}
 func NewFileFiller(opts ...FileFillerOpts) FileFiller {
 	s := FileFiller{}
 	for _, f := range opts {
@@ -59,31 +104,120 @@ func (s FileFiller) AddConfigFile(path string, keyPath []string) (Filler, error)
 		return nil, err
 	}
 	debug("source: adding config file", path)
-	return FileFiller{
-		source:          nflex.CombineSources(s.source, source),
-		umarshalOptions: s.umarshalOptions,
-	}, nil
+	n := s.addSource(path, source, keyPath)
+	n.origins[len(n.origins)-1].positions = s.readPositions(path)
+	return n, nil
+}
+
+// AddConfigSource is invoked by Registry.ConfigMap and Registry.ConfigValue
+// to add data that is layered the same way as files.
+func (s FileFiller) AddConfigSource(source nflex.Source, keyPath []string) (Filler, error) {
+	return s.addSource("configuration value", source, keyPath), nil
+}
+
+func (s FileFiller) addSource(name string, source nflex.Source, keyPath []string) FileFiller {
+	source = nflex.NewPrefixSource(newMatchingSource(source, s.matching), keyPath...)
+	origins := make([]fileOrigin, len(s.origins), len(s.origins)+1)
+	copy(origins, s.origins)
+	return s.with(nflex.CombineSources(s.source, source), append(origins, fileOrigin{name: name, source: source, prefix: keyPath}))
+}
+
+// with returns a copy of s that has a different source
+func (s FileFiller) with(source nflex.Source, origins []fileOrigin) FileFiller {
+	s.source = source
+	s.origins = origins
+	return s
+}
+
+// describeSource names the file that provides the value at the current
+// key path, like "config.yaml:3:9: db.port"
+func (s FileFiller) describeSource(t reflect.Type, tag reflectutils.Tag, firstFirst bool) string {
+	return s.origin(firstFirst)
 }
 
 type fileTag struct {
-	Name string `pt:"0"`
+	Name  string `pt:"0"`
+	Enum  string `pt:"enum"`
+	Alias string `pt:"alias"`
 }
 
 // Recurse is part of the CanRecurseFiller contract and is called by registry.Configure()
 func (s FileFiller) Recurse(name string) (Filler, error) {
+	return s.recurse(name, nil)
+}
+
+// recurse looks up name, or if a file does not have name, the first of
+// aliases that the file has.  The files are combined with
+// nflex.CombineSources so that they are layered the same way whether or
+// not aliases are used.
+func (s FileFiller) recurse(name string, aliases []string) (Filler, error) {
 	if s.source == nil {
 		debug("source: recurse", name, "-> no filler(nil) from", callers(8))
 		return nil, nil
 	}
-	source := s.source.Recurse(name)
+	origins := make([]fileOrigin, 0, len(s.origins))
+	parents := make([]nflex.Source, len(s.origins))
+	var offset int
+	for i, origin := range s.origins {
+		recursed, key, err := s.recurseOrigin(origin, name, aliases)
+		if err != nil {
+			return nil, err
+		}
+		parents[i] = origin.source
+		if key != name {
+			parents[i] = renameSource{source: origin.source, from: name, to: key}
+		}
+		if recursed.source == nil {
+			continue
+		}
+		// the same as nflex.MultiSource: slice indexes continue from
+		// one file to the next
+		if recursed.source.Type() == nflex.Slice {
+			length, _ := recursed.source.Len()
+			if offset != 0 {
+				recursed.source = nflex.WithOffset(recursed.source, offset)
+				recursed.offset = offset
+			}
+			offset += length
+		}
+		origins = append(origins, recursed)
+	}
+	source := nflex.CombineSources(parents...).Recurse(name)
 	if source == nil {
 		debug("source: recurse", name, "-> does not exist(nil) from", callers(8))
 		return nil, nil
 	}
 	debug("source: recurse", name, "from", callers(4))
-	return FileFiller{
-		source:          nflex.NewMultiSource(source),
-		umarshalOptions: s.umarshalOptions,
+	return s.with(nflex.NewMultiSource(source), origins), nil
+}
+
+// recurse looks up name in one file.  The source of the returned
+// fileOrigin is nil if the file does not have name.  Keys that are part
+// of the prefix are not in the file so they are not part of the key path.
+func (o fileOrigin) recurse(name string) (fileOrigin, error) {
+	if len(o.prefix) != 0 {
+		o.source = o.source.Recurse(name)
+		o.prefix = o.prefix[1:]
+		return o, nil
+	}
+	key := name
+	if o.offset != 0 {
+		if i, err := strconv.Atoi(name); err == nil {
+			key = strconv.Itoa(i - o.offset)
+		}
+	}
+	if ms, ok := o.source.(matchingSource); ok {
+		var err error
+		key, err = ms.resolve(name)
+		if err != nil {
+			return fileOrigin{}, commonerrors.ConfigurationError(errors.Wrap(err, o.describe()))
+		}
+	}
+	return fileOrigin{
+		name:      o.name,
+		source:    o.source.Recurse(name),
+		positions: o.positions,
+		keyPath:   appendKey(o.keyPath, key),
 	}, nil
 }
 
@@ -120,8 +254,29 @@ func (s FileFiller) Fill(
 	tag reflectutils.Tag,
 	firstFirst bool,
 	combineObjects bool,
-) (bool, error) {
+) (filled bool, err error) {
 	debug("source: fill into", t, tag, "first", firstFirst, "combine", combineObjects)
+	defer func() {
+		if err != nil {
+			if origin := s.origin(firstFirst); origin != "" {
+				err = errors.Wrap(err, origin)
+			}
+		}
+	}()
+	if tag.Tag != "" {
+		var fileTag fileTag
+		if tag.Fill(&fileTag) == nil && fileTag.Enum != "" {
+			defer func() {
+				if filled && err == nil {
+					err = checkEnum(fileTag.Enum, v)
+					if err != nil {
+						filled = false
+						err = commonerrors.ConfigurationError(err)
+					}
+				}
+			}()
+		}
+	}
 	source := nflex.MultiSourceSetFirst(firstFirst).
 		Combine(nflex.MultiSourceSetCombine(combineObjects)).
 		Apply(s.source)
@@ -1,5 +1,12 @@
 package nfigure
 
+import (
+	"reflect"
+	"strings"
+
+	"github.com/muir/reflectutils"
+)
+
 func prependSpace(s string) string {
 	if s != "" {
 		return " " + s
@@ -7,6 +14,13 @@ func prependSpace(s string) string {
 	return ""
 }
 
+func prependColon(s string) string {
+	if s != "" {
+		return ": " + s
+	}
+	return ""
+}
+
 func repeatString(s string, count int) []string {
 	r := make([]string, count)
 	for i := 0; i < count; i++ {
@@ -24,3 +38,16 @@ func notEmpty(strings ...string) []string {
 	}
 	return n
 }
+
+// fieldPath turns a field index, as provided by reflectutils.WalkStructElements,
+// into a dot-separated path of Go field names
+func fieldPath(t reflect.Type, index []int) string {
+	t = reflectutils.NonPointer(t)
+	names := make([]string, len(index))
+	for i, idx := range index {
+		f := t.Field(idx)
+		names[i] = f.Name
+		t = f.Type
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,158 @@
+package nfigure
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/muir/commonerrors"
+	"github.com/muir/reflectutils"
+	"github.com/pkg/errors"
+)
+
+// validationErrors are the per-field errors from validating a model
+type validationErrors []error
+
+func (v validationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, err := range v {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (v validationErrors) Unwrap() []error { return v }
+
+// validate runs the Validate from WithValidate.  When the model is
+// not valid, the fields are validated one at a time with StructPartial
+// so that each error can be blamed on where the field's value came
+// from: "$DB_PORT", "--db-port", or "config.yaml: db.port".
+//
+// Errors for fields that were filled from the command line are returned
+// as a commonerrors.UsageError, ahead of the rest, which are returned as a
+// commonerrors.ValidationError.
+func (r *Request) validate(t reflect.Type, v reflect.Value) error {
+	validator, ok := r.getValidator()
+	if !ok {
+		return nil
+	}
+	err := validator.Struct(r.object)
+	if err == nil {
+		return nil
+	}
+	var fieldErrors validationErrors
+	var flagErrors validationErrors
+	for _, path := range validationFields("", v) {
+		fieldErr := validator.StructPartial(r.object, path)
+		if fieldErr == nil {
+			continue
+		}
+		fieldErr = errors.Wrap(fieldErr, r.describeField(path))
+		if r.filledByFlags(path) {
+			flagErrors = append(flagErrors, fieldErr)
+		} else {
+			fieldErrors = append(fieldErrors, fieldErr)
+		}
+	}
+	switch {
+	case len(flagErrors) != 0 && len(fieldErrors) != 0:
+		return validationErrors{
+			flagErrors.as(commonerrors.UsageError),
+			fieldErrors.as(commonerrors.ValidationError),
+		}
+	case len(flagErrors) != 0:
+		return flagErrors.as(commonerrors.UsageError)
+	case len(fieldErrors) != 0:
+		return fieldErrors.as(commonerrors.ValidationError)
+	default:
+		// struct-level validation
+		return commonerrors.ValidationError(errors.Wrap(err, t.String()))
+	}
+}
+
+// as wraps the errors with kind, which is something like
+// commonerrors.ValidationError
+func (v validationErrors) as(kind func(error) error) error {
+	if len(v) == 1 {
+		return kind(v[0])
+	}
+	return kind(v)
+}
+
+// validationFields lists the paths of the fields in a struct, descending
+// into nested structs.  Arrays, slices, and maps are validated as a whole.
+func validationFields(path string, v reflect.Value) []string {
+	var paths []string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fieldPath := joinPath(path, f.Name)
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				paths = append(paths, fieldPath)
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			if _, err := reflectutils.MakeStringSetter(fv.Type()); err != nil {
+				paths = append(paths, validationFields(fieldPath, fv)...)
+				continue
+			}
+		}
+		paths = append(paths, fieldPath)
+	}
+	return paths
+}
+
+// filledByFlags is true if the value at path, a larger value that
+// contains it, or any of its elements came from a FlagHandler.
+func (r *Request) filledByFlags(path string) bool {
+	fillers := r.getFillers()
+	for p, tag := range r.provenance {
+		if p != path && !strings.HasPrefix(p, path+"[") && !strings.HasPrefix(path, p+".") && !strings.HasPrefix(path, p+"[") {
+			continue
+		}
+		if _, ok := fillers.m[tag].(*FlagHandler); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// describeField names where the value at path came from.  Values that
+// were filled as part of a larger value are blamed on the larger value.
+// Arrays, slices, and maps that were filled element by element are
+// blamed on where the elements came from.
+func (r *Request) describeField(path string) string {
+	var elementSources []string
+	seen := make(map[string]struct{})
+	for p, source := range r.sources {
+		if !strings.HasPrefix(p, path+"[") {
+			continue
+		}
+		if _, ok := seen[source]; !ok {
+			seen[source] = struct{}{}
+			elementSources = append(elementSources, source)
+		}
+	}
+	if len(elementSources) != 0 {
+		sort.Strings(elementSources)
+		return strings.Join(elementSources, ", ")
+	}
+	for p := path; p != ""; {
+		if source, ok := r.sources[p]; ok {
+			return source
+		}
+		i := strings.LastIndexAny(p, ".[")
+		if i == -1 {
+			break
+		}
+		p = p[:i]
+	}
+	return path
+}
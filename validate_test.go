@@ -0,0 +1,115 @@
+package nfigure
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/muir/commonerrors"
+	"github.com/muir/nflex"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validateDB struct {
+	Host string `flag:"host" validate:"required"`
+	Port int    `flag:"port" env:"VALIDATE_DB_PORT" validate:"max=65535"`
+}
+
+type validateConfig struct {
+	DB       validateDB `flag:"db" config:"database"`
+	Level    string     `config:"level" validate:"omitempty,oneof=debug info"`
+	Tags     []string   `flag:"tag" validate:"dive,max=3"`
+	Optional *struct {
+		Name string `validate:"required"`
+	}
+}
+
+func TestValidationErrorSources(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		config map[string]any
+		usage  bool
+		mixed  bool // both command line and other errors
+		error  []string
+	}{
+		{
+			name:  "env",
+			args:  []string{"--db-host", "h"},
+			env:   map[string]string{"VALIDATE_DB_PORT": "99999"},
+			error: []string{"$VALIDATE_DB_PORT: Key: 'validateConfig.DB.Port'"},
+		},
+		{
+			name:  "flag",
+			args:  []string{"--db-host", "h", "--db-port=70000"},
+			usage: true,
+			error: []string{"--db-port: Key: 'validateConfig.DB.Port'"},
+		},
+		{
+			name:   "file",
+			args:   []string{"--db-host", "h"},
+			config: map[string]any{"level": "loud"},
+			error:  []string{"configuration value: level: Key: 'validateConfig.Level'"},
+		},
+		{
+			name:  "slice element",
+			args:  []string{"--db-host", "h", "--tag", "ok", "--tag", "toolong"},
+			usage: true,
+			error: []string{"--tag: Key: 'validateConfig.Tags[1]'"},
+		},
+		{
+			name:   "flags first",
+			args:   []string{"--db-host", "h", "--db-port", "70000"},
+			config: map[string]any{"level": "loud"},
+			usage:  true,
+			mixed:  true,
+			error:  []string{"--db-port: Key: 'validateConfig.DB.Port'", "; configuration value: level: Key: 'validateConfig.Level'"},
+		},
+		{
+			name: "not filled and multiple",
+			env:  map[string]string{"VALIDATE_DB_PORT": "99999"},
+			error: []string{
+				"DB.Host: Key: 'validateConfig.DB.Host'",
+				"; $VALIDATE_DB_PORT: Key: 'validateConfig.DB.Port'",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			registry := NewRegistry(
+				WithFiller("flag", PosixFlagHandler(WithArgs(tc.args))),
+				WithValidate(validator.New()))
+			if tc.config != nil {
+				require.NoError(t, registry.ConfigMap(tc.config))
+			}
+			var got validateConfig
+			require.NoError(t, registry.Request(&got))
+			err := registry.Configure()
+			if assert.Error(t, err) {
+				for _, want := range tc.error {
+					assert.Contains(t, err.Error(), want)
+				}
+				assert.Equal(t, tc.usage, commonerrors.IsUsageError(err), "usage error")
+				assert.Equal(t, !tc.usage || tc.mixed, commonerrors.IsValidationError(err), "validation error")
+			}
+		})
+	}
+}
+
+func TestValidationErrorFile(t *testing.T) {
+	var got testDataF
+	registry := NewRegistry(
+		WithFiller("nf", NewFileFiller(WithUnmarshalOpts(nflex.WithFS(content)))),
+		WithValidate(validator.New()))
+	require.NoError(t, registry.ConfigFile("source7.yaml"))
+	require.NoError(t, registry.Request(&got, FromRoot("A", "B", "C")))
+	err := registry.Configure()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "source7.yaml: A.B.C.v: ")
+	}
+}